@@ -1,11 +1,23 @@
 package config
 
-import core "startup-manager/core/config"
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	core "startup-manager/core/config"
+)
+
+// EnvPrefix prefixes the environment variables overriding the config, e.g. STARTUP_MANAGER_DB_CONNECTION_STRING
+const EnvPrefix = "STARTUP_MANAGER"
+
+const defaultHttpPort = "6000"
 
 type Config struct {
-	AppConfig core.AppConfig `yaml:inline`
-	NomadURL  string         `json:"nomad_url" yaml:"nomad_url"`
-	HttpPort  string         `json:"http_port" yaml:"http_port"`
+	core.AppConfig `yaml:",inline"`
+	NomadURL       string `json:"nomad_url" yaml:"nomad_url" env:"NOMAD_URL"`
+	HttpPort       string `json:"http_port" yaml:"http_port" env:"HTTP_PORT"`
 }
 
 func (c *Config) GetAppConfig() *core.AppConfig {
@@ -15,3 +27,31 @@ func (c *Config) GetAppConfig() *core.AppConfig {
 func (c *Config) GetDbConfig() *core.DbConfig {
 	return c.AppConfig.DbConfig
 }
+
+func (c *Config) SetDefaults() {
+	if c.HttpPort == "" {
+		c.HttpPort = defaultHttpPort
+	}
+}
+
+// Validate checks the shared config and the fields of the startup manager
+func (c *Config) Validate() error {
+	var problems []string
+	if err := c.AppConfig.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if c.NomadURL == "" {
+		problems = append(problems, "nomad_url is required")
+	} else if u, err := url.Parse(c.NomadURL); err != nil || u.Scheme == "" || u.Host == "" {
+		problems = append(problems, "nomad_url must be an absolute url")
+	}
+	if port, err := strconv.Atoi(c.HttpPort); err != nil || port <= 0 || port > 65535 {
+		problems = append(problems, "http_port must be a port number")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+	"startup-manager/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReconcileReport returns the findings of the latest reconciler run
+func (sc *StartupController) GetReconcileReport(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"report": sc.usecase.LastReconcileReport()})
+}
+
+// Reconcile runs the reconciler now. The body may override the policy, dry_run=true only reports.
+func (sc *StartupController) Reconcile(ctx *gin.Context) {
+	policy := usecase.DefaultReconcilePolicy
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.BindJSON(&policy); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if ctx.Query("dry_run") == "true" {
+		policy = usecase.ReconcilePolicy{}
+	}
+
+	report := sc.usecase.Reconcile(ctx, policy)
+	ctx.JSON(http.StatusOK, gin.H{"report": report})
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"startup-manager/core/auth"
+	"startup-manager/usecase"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// queryTokenRoutes are the streaming routes that accept the token as the access_token query parameter,
+// browsers can not set headers on event streams and websockets
+var queryTokenRoutes = map[string]bool{
+	"/servers/:id/logs":      true,
+	"/servers/:id/console":   true,
+	"/operations/:id/stream": true,
+}
+
+// authenticate resolves the bearer token of the request to its user and stores it in the request context.
+// Only the streaming routes read the token from the query string.
+func (sc *StartupController) authenticate(ctx *gin.Context) {
+	token := bearerToken(ctx.GetHeader("Authorization"))
+	if token == "" && ctx.Request.Method == http.MethodGet && queryTokenRoutes[ctx.FullPath()] {
+		token = ctx.Query("access_token")
+	}
+
+	user, err := sc.usecase.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUnauthenticated) {
+			sc.logger.Debug("rejected request", zap.String("path", ctx.Request.URL.Path), zap.Error(err))
+		} else {
+			sc.logger.Error("failed to authenticate request", zap.Error(err))
+		}
+		respondError(ctx, err)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Request = ctx.Request.WithContext(auth.WithUser(ctx.Request.Context(), user))
+	ctx.Next()
+}
+
+// requireRole rejects requests of users without the role
+func (sc *StartupController) requireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := usecase.RequireRole(ctx, role)
+		if err != nil {
+			respondError(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// requestLogger logs requests like gin's default logger with the access_token query parameter redacted
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			redactAccessToken(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactAccessToken replaces the value of the access_token query parameter of a path
+func redactAccessToken(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base
+	}
+	if _, ok := query["access_token"]; !ok {
+		return path
+	}
+	query.Set("access_token", "REDACTED")
+	return base + "?" + query.Encode()
+}
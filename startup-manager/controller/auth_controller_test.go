@@ -0,0 +1,24 @@
+package controller
+
+import "testing"
+
+func TestRedactAccessToken(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/servers", "/servers"},
+		{"/servers?limit=10", "/servers?limit=10"},
+		{"/servers/1/logs?access_token=secret", "/servers/1/logs?access_token=REDACTED"},
+		{"/servers/1/logs?follow=true&access_token=secret", "/servers/1/logs?access_token=REDACTED&follow=true"},
+		{"/servers/1/logs?access_token=a&access_token=b", "/servers/1/logs?access_token=REDACTED"},
+		{"/servers/1/logs?access_token=%zz", "/servers/1/logs"},
+	}
+
+	for _, tt := range tests {
+		got := redactAccessToken(tt.path)
+		if got != tt.want {
+			t.Errorf("redactAccessToken(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
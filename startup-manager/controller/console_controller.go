@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	nomadapi "startup-manager/core/nomad"
+	"startup-manager/usecase"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func (sc *StartupController) consoleUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     sc.checkConsoleOrigin,
+	}
+}
+
+// checkConsoleOrigin accepts requests without an Origin, from the service's own host and from the allowed
+// hosts. A browser sends the access token of the query string from any page, so the "*" cors wildcard is
+// not honored here.
+func (sc *StartupController) checkConsoleOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, host := range sc.allowedHosts {
+		if strings.EqualFold(u.Host, host) || strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// originHosts reduces the configured domains, which may be full origins, to their hosts
+func originHosts(domains []string) []string {
+	hosts := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain == "" || domain == "*" {
+			continue
+		}
+		if u, err := url.Parse(domain); err == nil && u.Host != "" {
+			domain = u.Host
+		}
+		hosts = append(hosts, domain)
+	}
+	return hosts
+}
+
+// ConsoleMessage is exchanged as a json text frame over the console websocket.
+// Clients send stdin and resize messages, the server answers with stdout, stderr, exit and error messages.
+type ConsoleMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+	Code *int   `json:"code,omitempty"`
+}
+
+// ServerConsole upgrades to a websocket and bridges it to an exec session in the server's game task
+func (sc *StartupController) ServerConsole(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	command, err := sc.usecase.ResolveConsoleCommand(ctx, serverID, ctx.Query("command"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	conn, err := sc.consoleUpgrader().Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		sc.logger.Warn("cannot upgrade console connection", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	send := func(message ConsoleMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(message)
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan nomadapi.TerminalSize, 1)
+	sessionCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+
+	// the read loop ends when the client disconnects, which closes stdin and cancels the exec session
+	go func() {
+		defer cancel()
+		defer stdinWriter.Close()
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var message ConsoleMessage
+			if err := json.Unmarshal(payload, &message); err != nil {
+				send(ConsoleMessage{Type: "error", Data: "invalid message"})
+				continue
+			}
+
+			switch message.Type {
+			case "stdin":
+				if _, err := stdinWriter.Write([]byte(message.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if message.Cols <= 0 || message.Rows <= 0 {
+					continue
+				}
+				size := nomadapi.TerminalSize{Width: message.Cols, Height: message.Rows}
+				// keep only the latest size if the session has not picked up the previous one
+				select {
+				case <-resize:
+				default:
+				}
+				resize <- size
+			default:
+				send(ConsoleMessage{Type: "error", Data: "unknown message type " + message.Type})
+			}
+		}
+	}()
+
+	exitCode, err := sc.usecase.RunConsole(sessionCtx, serverID, usecase.ConsoleRequest{
+		Command:    command,
+		RemoteAddr: ctx.ClientIP(),
+		Stdin:      stdinReader,
+		Stdout:     consoleWriter{stream: "stdout", send: send},
+		Stderr:     consoleWriter{stream: "stderr", send: send},
+		Resize:     resize,
+	})
+	if err != nil {
+		if sessionCtx.Err() == nil {
+			send(ConsoleMessage{Type: "error", Data: err.Error()})
+		}
+	} else {
+		send(ConsoleMessage{Type: "exit", Code: &exitCode})
+	}
+
+	writeMu.Lock()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	writeMu.Unlock()
+}
+
+// consoleWriter forwards exec output to the websocket as console messages
+type consoleWriter struct {
+	stream string
+	send   func(ConsoleMessage) error
+}
+
+func (w consoleWriter) Write(p []byte) (int, error) {
+	err := w.send(ConsoleMessage{Type: w.stream, Data: string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -2,7 +2,9 @@ package controller
 
 import (
 	"net/http"
+	"startup-manager/core/auth"
 	"startup-manager/core/logger"
+	"startup-manager/core/probe"
 	"startup-manager/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -11,19 +13,33 @@ import (
 type StartupController struct {
 	logger  logger.Logger
 	usecase *usecase.StartUpUsecase
+	probe   *probe.Probe
 	httpMux *http.ServeMux
+	// allowedHosts may open console websockets besides the service's own host
+	allowedHosts []string
 }
 
-func NewStartupController(logger logger.Logger, usecase *usecase.StartUpUsecase) *StartupController {
+// NewStartupController creates the controller, allowedOrigins are the domains whose pages may open
+// console websockets
+func NewStartupController(logger logger.Logger, usecase *usecase.StartUpUsecase, probe *probe.Probe, allowedOrigins []string) *StartupController {
 	return &StartupController{
-		logger:  logger,
-		usecase: usecase,
-		httpMux: http.NewServeMux(),
+		logger:       logger,
+		usecase:      usecase,
+		probe:        probe,
+		httpMux:      http.NewServeMux(),
+		allowedHosts: originHosts(allowedOrigins),
 	}
 }
 
 func (sc *StartupController) registerRoutes() {
-	router := gin.Default()
+	// gin's default logger would write access tokens of the query string to the log
+	router := gin.New()
+	router.Use(requestLogger(), gin.Recovery())
+	// usecases read the authenticated user from the request context through the gin context
+	router.ContextWithFallback = true
+	router.Use(sc.authenticate)
+	adminOnly := sc.requireRole(auth.RoleAdmin)
+
 	startupRoute := router.Group("/")
 
 	startupRoute.POST("/addstartup", sc.AddStartupHandler)
@@ -32,15 +48,56 @@ func (sc *StartupController) registerRoutes() {
 	startupRoute.GET("/getDefaultParameters",sc.GetGameEnvironments)
 	startupRoute.GET("/get_game_info",sc.GetGameInfo)
 	startupRoute.GET("/get_default_command",sc.GetDefaultStartupCommand)
+	startupRoute.PUT("/game_job_template", adminOnly, sc.UpdateGameJobTemplate)
+
+	gameRoute := router.Group("/games")
+	gameRoute.POST("", adminOnly, sc.CreateGame)
+	gameRoute.GET("", sc.ListGames)
+	gameRoute.GET("/:id", sc.GetGame)
+	gameRoute.PUT("/:id", adminOnly, sc.UpdateGame)
+	gameRoute.DELETE("/:id", adminOnly, sc.DeleteGame)
+
+	serverRoute := router.Group("/servers")
+	serverRoute.POST("", sc.CreateServer)
+	serverRoute.GET("", sc.ListServers)
+	serverRoute.GET("/:id", sc.GetServer)
+	serverRoute.PATCH("/:id", sc.RenameServer)
+	serverRoute.PATCH("/:id/resources", sc.ResizeServer)
+	serverRoute.POST("/:id/start", sc.StartServer)
+	serverRoute.POST("/:id/stop", sc.StopServer)
+	serverRoute.POST("/:id/restart", sc.RestartServer)
+	serverRoute.DELETE("/:id", sc.DeleteServer)
+	serverRoute.GET("/:id/logs", sc.StreamServerLogs)
+	serverRoute.GET("/:id/console", sc.ServerConsole)
+	serverRoute.GET("/:id/stats", sc.GetServerStats)
+	serverRoute.GET("/:id/connection", sc.GetServerConnection)
+	serverRoute.POST("/:id/startup/preview", sc.PreviewStartup)
+	serverRoute.GET("/:id/startup/history", sc.StartupHistory)
+	serverRoute.POST("/:id/startup/rollback/:revision", sc.RollbackStartup)
+
+	meRoute := router.Group("/me")
+	meRoute.GET("/quota", sc.GetQuota)
+
+	operationRoute := router.Group("/operations")
+	operationRoute.GET("/:id", sc.GetOperation)
+	operationRoute.GET("/:id/stream", sc.StreamOperation)
+
+	adminRoute := router.Group("/admin", adminOnly)
+	adminRoute.GET("/reconcile", sc.GetReconcileReport)
+	adminRoute.POST("/reconcile", sc.Reconcile)
+	adminRoute.GET("/plans", sc.ListPlans)
+	adminRoute.PUT("/quotas/:user_id", sc.SetUserQuota)
 	sc.httpMux.Handle("/", router)
+	sc.httpMux.Handle(sc.probe.HealthPath(), sc.probe)
+	sc.httpMux.Handle(sc.probe.ReadyPath(), sc.probe)
 
 }
 
-func (sc *StartupController) Start() error {
+func (sc *StartupController) Start(addr string) error {
 	sc.registerRoutes()
 	server := http.Server{
 		Handler: sc.httpMux,
-		Addr:    ":6000",
+		Addr:    addr,
 	}
 	return server.ListenAndServe()
 }
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"net/http"
+	"startup-manager/core/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func (sc *StartupController) CreateGame(ctx *gin.Context) {
+	var request GameRequest
+
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	game, err := sc.usecase.CreateGame(ctx, request.toGame())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"game": game})
+}
+
+func (sc *StartupController) ListGames(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	page, err := sc.usecase.ListGames(ctx, ctx.Query("search"), limit, offset)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (sc *StartupController) GetGame(ctx *gin.Context) {
+	gameID, ok := parseGameID(ctx)
+	if !ok {
+		return
+	}
+
+	game, err := sc.usecase.GetGameByID(ctx, gameID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"game": game})
+}
+
+func (sc *StartupController) UpdateGame(ctx *gin.Context) {
+	gameID, ok := parseGameID(ctx)
+	if !ok {
+		return
+	}
+
+	var request GameRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	game := request.toGame()
+	game.ID = gameID
+	updated, err := sc.usecase.UpdateGame(ctx, game)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"game": updated})
+}
+
+func (sc *StartupController) DeleteGame(ctx *gin.Context) {
+	gameID, ok := parseGameID(ctx)
+	if !ok {
+		return
+	}
+
+	err := sc.usecase.DeleteGame(ctx, gameID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"game_id": gameID, "status": "game deleted"})
+}
+
+func parseGameID(ctx *gin.Context) (string, bool) {
+	gameID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return "", false
+	}
+	return gameID.String(), true
+}
+
+type GameRequest struct {
+	Name                  string                `json:"name"`
+	Description           string                `json:"description"`
+	Image                 string                `json:"image"`
+	Envs                  []string              `json:"envs"`
+	Ports                 []int32               `json:"ports"`
+	PortLabels            []string              `json:"port_labels"`
+	Volumes               []string              `json:"volumes"`
+	CPU                   int                   `json:"cpu"`
+	Memory                int                   `json:"memory"`
+	Disk                  int                   `json:"disk"`
+	Command               string                `json:"command"`
+	Args                  []string              `json:"args"`
+	DefaultStartupCommand string                `json:"default_startup_command"`
+	DefaultVariables      []string              `json:"default_variables"`
+	WithDB                bool                  `json:"with_db"`
+	JobTemplate           string                `json:"job_template"`
+	ConsoleCommands       []string              `json:"console_commands"`
+	Variables             models.VariableSchema `json:"variables"`
+	ConnectTemplate       string                `json:"connect_template"`
+}
+
+func (r GameRequest) toGame() *models.Game {
+	return &models.Game{
+		Name:                  r.Name,
+		Description:           r.Description,
+		Image:                 r.Image,
+		Envs:                  pq.StringArray(r.Envs),
+		Ports:                 pq.Int32Array(r.Ports),
+		PortLabels:            pq.StringArray(r.PortLabels),
+		Volumes:               pq.StringArray(r.Volumes),
+		CPU:                   r.CPU,
+		Memory:                r.Memory,
+		Disk:                  r.Disk,
+		Command:               r.Command,
+		Args:                  pq.StringArray(r.Args),
+		DefaultStartupCommand: r.DefaultStartupCommand,
+		DefaultVariables:      pq.StringArray(r.DefaultVariables),
+		WithDB:                r.WithDB,
+		JobTemplate:           r.JobTemplate,
+		ConsoleCommands:       pq.StringArray(r.ConsoleCommands),
+		Variables:             r.Variables,
+		ConnectTemplate:       r.ConnectTemplate,
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"startup-manager/usecase"
+	"strconv"
+	"strings"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StreamServerLogs streams the server console as server sent events. Every event id is a cursor
+// that is sent back in the Last-Event-ID header, or the cursor query parameter, to resume after a reconnect.
+func (sc *StartupController) StreamServerLogs(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	rawCursor := ctx.GetHeader("Last-Event-ID")
+	if rawCursor == "" {
+		rawCursor = ctx.Query("cursor")
+	}
+	cursor, err := parseLogCursor(rawCursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	err = sc.usecase.StreamServerLogs(ctx.Request.Context(), serverID, cursor, func(event usecase.LogEvent) error {
+		data := gin.H{"alloc_id": event.Cursor.AllocID}
+		if event.Type == usecase.LogEventOutput {
+			data["stream"] = event.Stream
+			data["data"] = string(event.Data)
+		}
+
+		err := sse.Encode(ctx.Writer, sse.Event{
+			Id:    formatLogCursor(event.Cursor),
+			Event: event.Type,
+			Data:  data,
+		})
+		if err != nil {
+			return err
+		}
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		sc.logger.Warn("log stream closed", zap.String("server_id", serverID.String()), zap.Error(err))
+		if !ctx.Writer.Written() {
+			respondError(ctx, err)
+			return
+		}
+		sse.Encode(ctx.Writer, sse.Event{Event: "error", Data: gin.H{"error": err.Error()}})
+		ctx.Writer.Flush()
+	}
+}
+
+// formatLogCursor encodes the cursor as alloc_id:stdout_offset:stderr_offset
+func formatLogCursor(cursor usecase.LogCursor) string {
+	return fmt.Sprintf("%s:%d:%d", cursor.AllocID, cursor.Stdout, cursor.Stderr)
+}
+
+func parseLogCursor(raw string) (usecase.LogCursor, error) {
+	if raw == "" {
+		return usecase.LogCursor{}, nil
+	}
+
+	parts := strings.Split(raw, ":")
+	if len(parts) != 3 {
+		return usecase.LogCursor{}, fmt.Errorf("invalid log cursor %q", raw)
+	}
+	stdout, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || stdout < 0 {
+		return usecase.LogCursor{}, fmt.Errorf("invalid stdout offset in log cursor %q", raw)
+	}
+	stderr, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || stderr < 0 {
+		return usecase.LogCursor{}, fmt.Errorf("invalid stderr offset in log cursor %q", raw)
+	}
+
+	return usecase.LogCursor{AllocID: parts[0], Stdout: stdout, Stderr: stderr}, nil
+}
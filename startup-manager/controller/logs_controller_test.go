@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"startup-manager/usecase"
+	"testing"
+)
+
+func TestParseLogCursor(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    usecase.LogCursor
+		wantErr bool
+	}{
+		{raw: "", want: usecase.LogCursor{}},
+		{raw: "alloc-1:0:0", want: usecase.LogCursor{AllocID: "alloc-1"}},
+		{raw: "alloc-1:120:7", want: usecase.LogCursor{AllocID: "alloc-1", Stdout: 120, Stderr: 7}},
+		{raw: ":5:6", want: usecase.LogCursor{Stdout: 5, Stderr: 6}},
+
+		{raw: "alloc-1", wantErr: true},
+		{raw: "alloc-1:5", wantErr: true},
+		{raw: "alloc-1:5:6:7", wantErr: true},
+		{raw: "alloc-1:x:6", wantErr: true},
+		{raw: "alloc-1:5:", wantErr: true},
+		{raw: "alloc-1:-1:6", wantErr: true},
+		{raw: "alloc-1:5:-6", wantErr: true},
+		{raw: "alloc-1:9223372036854775808:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLogCursor(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLogCursor(%q) = %+v, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLogCursor(%q): unexpected error: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseLogCursor(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+		if tt.raw != "" && formatLogCursor(got) != tt.raw {
+			t.Errorf("formatLogCursor(%+v) = %q, want %q", got, formatLogCursor(got), tt.raw)
+		}
+	}
+}
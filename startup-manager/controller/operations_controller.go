@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+	"startup-manager/core/models"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func (sc *StartupController) GetOperation(ctx *gin.Context) {
+	operationID, ok := parseOperationID(ctx)
+	if !ok {
+		return
+	}
+
+	operation, err := sc.usecase.GetOperation(ctx, operationID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"operation": operation})
+}
+
+// StreamOperation sends the operation as a server sent event every time it changes and closes the
+// stream once the operation is done
+func (sc *StartupController) StreamOperation(ctx *gin.Context) {
+	operationID, ok := parseOperationID(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	err := sc.usecase.WatchOperation(ctx.Request.Context(), operationID, func(operation *models.Operation) error {
+		err := sse.Encode(ctx.Writer, sse.Event{
+			Event: "operation",
+			Data:  operation,
+		})
+		if err != nil {
+			return err
+		}
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		sc.logger.Warn("operation stream closed", zap.String("operation_id", operationID.String()), zap.Error(err))
+		if !ctx.Writer.Written() {
+			respondError(ctx, err)
+		}
+	}
+}
+
+// respondOperation answers 202 with the queued operation and where to poll it
+func respondOperation(ctx *gin.Context, operation *models.Operation) {
+	ctx.Header("Location", "/operations/"+operation.ID.String())
+	ctx.JSON(http.StatusAccepted, gin.H{"operation": operation})
+}
+
+// parseOperationID reads the :id path parameter and answers 400 when it is not a uuid
+func parseOperationID(ctx *gin.Context) (uuid.UUID, bool) {
+	operationID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid operation id"})
+		return uuid.Nil, false
+	}
+	return operationID, true
+}
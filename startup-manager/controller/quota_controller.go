@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"net/http"
+	"startup-manager/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuota returns the caller's quota and how much of it their servers use
+func (sc *StartupController) GetQuota(ctx *gin.Context) {
+	quota, err := sc.usecase.GetQuota(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"quota": quota})
+}
+
+func (sc *StartupController) ListPlans(ctx *gin.Context) {
+	plans, err := sc.usecase.ListPlans(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"plans": plans})
+}
+
+// SetUserQuota puts the user on a plan, limits in the body override the plan's
+func (sc *StartupController) SetUserQuota(ctx *gin.Context) {
+	var request usecase.UserQuotaRequest
+
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quota, err := sc.usecase.SetUserQuota(ctx, ctx.Param("user_id"), request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"quota": quota})
+}
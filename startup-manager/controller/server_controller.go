@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"startup-manager/usecase"
+	"startup-manager/usecase/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (sc *StartupController) CreateServer(ctx *gin.Context) {
+	var request CreateServerRequest
+
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	server, err := sc.usecase.CreateServer(ctx, usecase.CreateServerRequest{
+		UserID:     request.UserID,
+		ServerName: request.ServerName,
+		GameID:     request.GameID,
+		GameName:   request.Game,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"server": server})
+}
+
+func (sc *StartupController) ListServers(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	page, err := sc.usecase.ListServers(ctx, repository.ServerFilter{
+		UserID:   ctx.Query("user_id"),
+		GameName: ctx.Query("game"),
+		Status:   ctx.Query("status"),
+		Search:   ctx.Query("search"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (sc *StartupController) GetServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	details, err := sc.usecase.GetServerDetails(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, details)
+}
+
+func (sc *StartupController) GetServerStats(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	stats, err := sc.usecase.GetServerStats(ctx, serverID, ctx.Query("range"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+func (sc *StartupController) GetServerConnection(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	connection, err := sc.usecase.GetServerConnection(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, connection)
+}
+
+func (sc *StartupController) RenameServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	var request RenameServerRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	server, err := sc.usecase.RenameServer(ctx, serverID, request.ServerName)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"server": server})
+}
+
+func (sc *StartupController) StartServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	operation, err := sc.usecase.StartServer(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	respondOperation(ctx, operation)
+}
+
+func (sc *StartupController) StopServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	operation, err := sc.usecase.StopServer(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	respondOperation(ctx, operation)
+}
+
+func (sc *StartupController) RestartServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	operation, err := sc.usecase.RestartServer(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	respondOperation(ctx, operation)
+}
+
+func (sc *StartupController) DeleteServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	operation, err := sc.usecase.DeleteServer(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	respondOperation(ctx, operation)
+}
+
+// ResizeServer changes the server's cpu and memory. dry_run=true only reports whether nomad can place the
+// server with them, a change nomad can not place is answered with 409 and the plan. Resizing a server
+// without a startup is answered with 409 as well.
+func (sc *StartupController) ResizeServer(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	var request usecase.ResourcesRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, operation, err := sc.usecase.ResizeServer(ctx, serverID, request, ctx.Query("dry_run") == "true")
+	if errors.Is(err, usecase.ErrResourcesUnplaceable) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "resources": change})
+		return
+	}
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if operation == nil {
+		ctx.JSON(http.StatusOK, gin.H{"resources": change})
+		return
+	}
+	ctx.Header("Location", "/operations/"+operation.ID.String())
+	ctx.JSON(http.StatusAccepted, gin.H{"operation": operation, "resources": change})
+}
+
+// parseServerID reads the :id path parameter and answers 400 when it is not a uuid
+func parseServerID(ctx *gin.Context) (uuid.UUID, bool) {
+	serverID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid server id"})
+		return uuid.Nil, false
+	}
+	return serverID, true
+}
+
+// errorStatus maps usecase errors to http status codes
+func errorStatus(err error) int {
+	var (
+		validationErr  *usecase.ValidationError
+		placeholderErr *usecase.PlaceholderError
+		quotaErr       *usecase.QuotaExceededError
+	)
+
+	switch {
+	case errors.As(err, &validationErr), errors.As(err, &placeholderErr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, usecase.ErrServerNotFound), errors.Is(err, usecase.ErrGameNotFound), errors.Is(err, usecase.ErrRevisionNotFound),
+		errors.Is(err, usecase.ErrOperationNotFound), errors.Is(err, usecase.ErrPlanNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, usecase.ErrCommandNotAllowed), errors.Is(err, usecase.ErrForbidden), errors.As(err, &quotaErr):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrGameExists), errors.Is(err, usecase.ErrGameInUse), errors.Is(err, usecase.ErrServerExists),
+		errors.Is(err, usecase.ErrServerNotRunning), errors.Is(err, usecase.ErrResourcesUnplaceable), errors.Is(err, usecase.ErrNoActiveStartup):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError writes the error with its status, validation errors carry their field errors
+func respondError(ctx *gin.Context, err error) {
+	var (
+		validationErr  *usecase.ValidationError
+		placeholderErr *usecase.PlaceholderError
+		quotaErr       *usecase.QuotaExceededError
+	)
+	if errors.As(err, &validationErr) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "validation failed", "fields": validationErr.Fields})
+		return
+	}
+	if errors.As(err, &placeholderErr) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "missing": placeholderErr.Missing, "unused": placeholderErr.Unused,
+			"quoted": placeholderErr.Quoted})
+		return
+	}
+	if errors.As(err, &quotaErr) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "quota": quotaErr})
+		return
+	}
+	status := errorStatus(err)
+	if status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Bearer realm="startup-manager"`)
+		// the cause is only logged, it would tell a caller which part of a forged token was rejected
+		err = usecase.ErrUnauthenticated
+	}
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
+
+type CreateServerRequest struct {
+	UserID     string `json:"user_id"`
+	ServerName string `json:"server_name"`
+	GameID     string `json:"game_id"`
+	Game       string `json:"game"`
+}
+
+type RenameServerRequest struct {
+	ServerName string `json:"server_name"`
+}
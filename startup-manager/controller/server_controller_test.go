@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"startup-manager/usecase"
+	"testing"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&usecase.ValidationError{}, http.StatusUnprocessableEntity},
+		{&usecase.PlaceholderError{}, http.StatusUnprocessableEntity},
+		{usecase.ErrServerNotFound, http.StatusNotFound},
+		{fmt.Errorf("loading server: %w", usecase.ErrServerNotFound), http.StatusNotFound},
+		{usecase.ErrGameNotFound, http.StatusNotFound},
+		{usecase.ErrRevisionNotFound, http.StatusNotFound},
+		{usecase.ErrOperationNotFound, http.StatusNotFound},
+		{usecase.ErrPlanNotFound, http.StatusNotFound},
+		{usecase.ErrUnauthenticated, http.StatusUnauthorized},
+		{usecase.ErrForbidden, http.StatusForbidden},
+		{usecase.ErrCommandNotAllowed, http.StatusForbidden},
+		{&usecase.QuotaExceededError{}, http.StatusForbidden},
+		{usecase.ErrGameExists, http.StatusConflict},
+		{usecase.ErrGameInUse, http.StatusConflict},
+		{usecase.ErrServerExists, http.StatusConflict},
+		{usecase.ErrServerNotRunning, http.StatusConflict},
+		{fmt.Errorf("%w: no nodes", usecase.ErrResourcesUnplaceable), http.StatusConflict},
+		{usecase.ErrNoActiveStartup, http.StatusConflict},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		got := errorStatus(tt.err)
+		if got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"startup-manager/core/models"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,12 +27,14 @@ func (sc *StartupController) AddStartupHandler(ctx *gin.Context) {
 		UpdatedAt:     &startupRequest.UpdatedAt,
 		DeletedAt:     &startupRequest.DeletedAt,
 	}
-	startup_id, err := sc.usecase.AddStartup(ctx, &startupInfo)
+	operation, err := sc.usecase.AddStartup(ctx, &startupInfo)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"startup_id": startup_id, "message": "startup added successfully"})
+	ctx.Header("Location", "/operations/"+operation.ID.String())
+	ctx.JSON(http.StatusAccepted, gin.H{"startup_id": startupInfo.ID, "revision": startupInfo.Revision, "apply_status": startupInfo.ApplyStatus,
+		"operation": operation, "message": "startup added successfully"})
 
 }
 
@@ -40,7 +43,7 @@ func(sc *StartupController)GetStartup(ctx *gin.Context){
 
 	startup,err:=sc.usecase.GetStartup(ctx,id)
 	if err!=nil{
-		ctx.JSON(http.StatusBadRequest,gin.H{"error":err})
+		respondError(ctx,err)
 		return
 	}
 	ctx.JSON(http.StatusOK,gin.H{"startup":startup})
@@ -91,6 +94,76 @@ func(sc *StartupController)GetDefaultStartupCommand(ctx *gin.Context){
 	ctx.JSON(http.StatusOK,gin.H{"command":command})
 }
 
+func (sc *StartupController) UpdateGameJobTemplate(ctx *gin.Context) {
+	var request GameJobTemplateRequest
+
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, err := sc.usecase.UpdateGameJobTemplate(ctx, request.Game, request.JobTemplate)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"game": request.Game, "job_template_version": version})
+}
+
+func (sc *StartupController) PreviewStartup(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	var request StartupVariablesRequest
+	if err := ctx.BindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := sc.usecase.PreviewStartup(ctx, serverID, request.Variables)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, preview)
+}
+
+func (sc *StartupController) StartupHistory(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	history, err := sc.usecase.StartupHistory(ctx, serverID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"revisions": history})
+}
+
+func (sc *StartupController) RollbackStartup(ctx *gin.Context) {
+	serverID, ok := parseServerID(ctx)
+	if !ok {
+		return
+	}
+
+	revision, err := strconv.Atoi(ctx.Param("revision"))
+	if err != nil || revision <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	operation, err := sc.usecase.RollbackStartup(ctx, serverID, revision)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	respondOperation(ctx, operation)
+}
+
 func convertMapToJSON(data map[string]interface{}) []byte {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -108,3 +181,12 @@ type StartupInfoRequest struct {
 	UpdatedAt     time.Time              `json:"updated_at"`
 	DeletedAt     time.Time              `json:"deleted_at"`
 }
+
+type GameJobTemplateRequest struct {
+	Game        string `json:"game" binding:"required"`
+	JobTemplate string `json:"job_template"`
+}
+
+type StartupVariablesRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	p256 *ecdsa.PrivateKey
+	p384 *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, p256: p256, p384: p384}
+}
+
+// newTestVerifier writes the public keys to a JWKS file as kids "rsa", "p256" and "p384"
+func newTestVerifier(t *testing.T, keys *testKeys) *JWTVerifier {
+	t.Helper()
+
+	encode := func(i *big.Int, size int) string {
+		return base64.RawURLEncoding.EncodeToString(i.FillBytes(make([]byte, size)))
+	}
+	ecKey := func(kid, crv string, key *ecdsa.PrivateKey) jwk {
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return jwk{Kty: "EC", Kid: kid, Use: "sig", Crv: crv, X: encode(key.X, size), Y: encode(key.Y, size)}
+	}
+	set := map[string][]jwk{"keys": {
+		{
+			Kty: "RSA",
+			Kid: "rsa",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(keys.rsa.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(keys.rsa.E)).Bytes()),
+		},
+		ecKey("p256", "P-256", keys.p256),
+		ecKey("p384", "P-384", keys.p384),
+	}}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	err = os.WriteFile(file, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewJWTVerifier(JWTConfig{JWKSFile: file, Issuer: "panel", Audience: "startup-manager", RolesClaim: "roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken signs header and claims with key, an ecdsa key signs with the hash of alg regardless of its curve
+func signToken(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	hash := crypto.SHA256
+	switch header["alg"] {
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case nil:
+	default:
+		t.Fatalf("unsupported key %T", key)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamper replaces the claims of a signed token and keeps its signature
+func tamper(t *testing.T, token string, claims map[string]interface{}) string {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + encodeSegment(t, claims) + "." + parts[2]
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys)
+	now := time.Unix(1700000000, 0)
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":   "user-1",
+			"iss":   "panel",
+			"aud":   "startup-manager",
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"admin"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	header := func(alg, kid string) map[string]interface{} {
+		return map[string]interface{}{"alg": alg, "kid": kid, "typ": "JWT"}
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "RS256", token: signToken(t, header("RS256", "rsa"), claims(nil), keys.rsa), valid: true},
+		{name: "RS512", token: signToken(t, header("RS512", "rsa"), claims(nil), keys.rsa), valid: true},
+		{name: "ES256 with P-256", token: signToken(t, header("ES256", "p256"), claims(nil), keys.p256), valid: true},
+		{name: "ES384 with P-384", token: signToken(t, header("ES384", "p384"), claims(nil), keys.p384), valid: true},
+		{name: "audience list", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"aud": []string{"other", "startup-manager"}}), keys.rsa), valid: true},
+		{name: "within clock skew", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()}), keys.rsa), valid: true},
+
+		{name: "expired", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}), keys.rsa)},
+		{name: "missing exp", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"exp": nil}), keys.rsa)},
+		{name: "not valid yet", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}), keys.rsa)},
+		{name: "wrong issuer", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"iss": "other"}), keys.rsa)},
+		{name: "wrong audience", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"aud": "other"}), keys.rsa)},
+		{name: "missing subject", token: signToken(t, header("RS256", "rsa"), claims(map[string]interface{}{"sub": nil}), keys.rsa)},
+		{name: "unknown kid", token: signToken(t, header("RS256", "other"), claims(nil), keys.rsa)},
+		{name: "alg none", token: signToken(t, header("none", "rsa"), claims(nil), nil)},
+		{name: "HS256 keyed with the public key", token: signToken(t, header("HS256", "rsa"), claims(nil), keys.rsa.PublicKey.N.Bytes())},
+		{name: "ES256 on an rsa key", token: signToken(t, header("ES256", "rsa"), claims(nil), keys.p256)},
+		{name: "RS256 on an ec key", token: signToken(t, header("RS256", "p256"), claims(nil), keys.rsa)},
+		{name: "ES256 with P-384", token: signToken(t, header("ES256", "p384"), claims(nil), keys.p384)},
+		{name: "ES384 with P-256", token: signToken(t, header("ES384", "p256"), claims(nil), keys.p256)},
+		{name: "signed by another key", token: signToken(t, header("ES384", "p384"), claims(nil), newTestKeys(t).p384)},
+		{name: "tampered claims", token: tamper(t, signToken(t, header("RS256", "rsa"), claims(nil), keys.rsa), claims(map[string]interface{}{"sub": "user-2"}))},
+		{name: "two parts", token: "a.b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := verifier.Verify(tt.token, now)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := &User{ID: "user-1", Roles: []string{"admin"}, Source: SourceJWT}
+			if !reflect.DeepEqual(user, want) {
+				t.Fatalf("user = %+v, want %+v", user, want)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureLength(t *testing.T) {
+	keys := newTestKeys(t)
+	token := signToken(t, map[string]interface{}{"alg": "ES256"}, map[string]interface{}{"sub": "user-1"}, keys.p256)
+	dot := strings.LastIndex(token, ".")
+	signed := token[:dot]
+	signature, err := base64.RawURLEncoding.DecodeString(token[dot+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifySignature("ES256", &keys.p256.PublicKey, signed, signature)
+	if err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	for _, length := range []int{0, 32, 63, 65, 96} {
+		resized := make([]byte, length)
+		copy(resized, signature)
+		err = verifySignature("ES256", &keys.p256.PublicKey, signed, resized)
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("signature of %d bytes: error = %v, want %v", length, err, ErrInvalidToken)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "context"
+
+// RoleAdmin grants access to every server and to the admin routes
+const RoleAdmin = "admin"
+
+// Ways a user was authenticated
+const (
+	SourceJWT    = "jwt"
+	SourceAPIKey = "api_key"
+)
+
+// User is the authenticated caller of a request
+type User struct {
+	ID     string   `json:"id"`
+	Roles  []string `json:"roles"`
+	Source string   `json:"source"`
+}
+
+// HasRole reports whether the user was granted the role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.HasRole(RoleAdmin)
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying the user
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user of the request ctx belongs to
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey{}).(*User)
+	return user, ok && user != nil
+}
@@ -2,49 +2,77 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
+// ServiceConfig is a service's config, it embeds AppConfig and validates its own fields. A service config
+// implementing SetDefaults() gets its defaults applied after the environment.
 type ServiceConfig interface {
 	GetAppConfig() *AppConfig
 	GetDbConfig() *DbConfig
+	Validate() error
 }
 
+// AppConfig is the config every service shares. The env tags name the environment variables that override
+// a field, prefixed with the service's env prefix.
 type AppConfig struct {
-	DbConfig            *DbConfig    `json:"db_config" yaml:"db_config"`
-	DomainName          string       `json:"domain_name" yaml:"domain_name"`
-	AuthorizerTableName string       `json:"authorizer_table_name" yaml:"authorizer_table_name"`
-	RedisConfig         *RedisConfig `json:"redis_config" yaml:"redis_config"`
-	ActivityManagerURL  string       `json:"activity_manager_url" yaml:"activity_manager_url"`
-	Probe               *ProbeConfig `json:"probe" yaml:"probe"`
-	CorsDomains         []string     `json:"cors_domains" yaml:"cors_domains"`
+	DbConfig            *DbConfig    `json:"db_config" yaml:"db_config" env:"DB"`
+	DomainName          string       `json:"domain_name" yaml:"domain_name" env:"DOMAIN_NAME"`
+	AuthorizerTableName string       `json:"authorizer_table_name" yaml:"authorizer_table_name" env:"AUTHORIZER_TABLE_NAME"`
+	RedisConfig         *RedisConfig `json:"redis_config" yaml:"redis_config" env:"REDIS"`
+	ActivityManagerURL  string       `json:"activity_manager_url" yaml:"activity_manager_url" env:"ACTIVITY_MANAGER_URL"`
+	Probe               *ProbeConfig `json:"probe" yaml:"probe" env:"PROBE"`
+	CorsDomains         []string     `json:"cors_domains" yaml:"cors_domains" env:"CORS_DOMAINS"`
+	Auth                *AuthConfig  `json:"auth" yaml:"auth" env:"AUTH"`
+}
+
+// AuthConfig configures bearer token authentication. Tokens are verified against the keys of JWKSFile,
+// without it only API keys from the authorizer table are accepted.
+type AuthConfig struct {
+	JWKSFile   string `json:"jwks_file" yaml:"jwks_file" env:"JWKS_FILE"`
+	Issuer     string `json:"issuer" yaml:"issuer" env:"ISSUER"`
+	Audience   string `json:"audience" yaml:"audience" env:"AUDIENCE"`
+	UserClaim  string `json:"user_claim" yaml:"user_claim" env:"USER_CLAIM"`
+	RolesClaim string `json:"roles_claim" yaml:"roles_claim" env:"ROLES_CLAIM"`
 }
 
 type RedisConfig struct {
-	URL      string `json:"url" yaml:"url"`
-	Password string `json:"password" yaml:"password"`
+	URL      string `json:"url" yaml:"url" env:"URL"`
+	Password string `json:"password" yaml:"password" env:"PASSWORD"`
 }
 
 type ProbeConfig struct {
-	Health string `json:"health" yaml:"health"`
-	Ready  string `json:"ready" yaml:"ready"`
-	Prefix string `json:"prefix" yaml:"prefix"`
+	Health string `json:"health" yaml:"health" env:"HEALTH"`
+	Ready  string `json:"ready" yaml:"ready" env:"READY"`
+	Prefix string `json:"prefix" yaml:"prefix" env:"PREFIX"`
 }
 
 type DbConfig struct {
-	ConnectionString string `json:"connection_string" yaml:"connection_string"`
-	MaxConnRetries   int    `json:"max_conn_retries" yaml:"max_conn_retries"`
-	MaxOpenConns     int    `json:"max_open_conns" yaml:"max_open_conns"`
-	MigrationsPath   string `json:"migrations_path" yaml:"migrations_path"`
+	ConnectionString string `json:"connection_string" yaml:"connection_string" env:"CONNECTION_STRING"`
+	MaxConnRetries   int    `json:"max_conn_retries" yaml:"max_conn_retries" env:"MAX_CONN_RETRIES"`
+	MaxOpenConns     int    `json:"max_open_conns" yaml:"max_open_conns" env:"MAX_OPEN_CONNS"`
+	// MigrationsPath overrides the migrations built into the binary
+	MigrationsPath string `json:"migrations_path" yaml:"migrations_path" env:"MIGRATIONS_PATH"`
+	// AutoMigrate applies pending migrations on boot, it defaults to true
+	AutoMigrate *bool `json:"auto_migrate" yaml:"auto_migrate" env:"AUTO_MIGRATE"`
 }
 
-// LoadConfig loads config from yml or json file
+// identifierRegex matches unquoted postgres identifiers, optionally schema qualified
+var identifierRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// LoadConfig loads the config in layers: the yml or json file, then environment variables named envPrefix_<env tag>,
+// then the overrides, usually the flags the service was started with. Defaults fill whatever is still empty
+// and the result is validated, an empty fileName skips the file.
 // you can load your own service config which has AppConfig in it
-func LoadConfig[T ServiceConfig](fileName string) (T, error) {
+func LoadConfig[T ServiceConfig](fileName, envPrefix string, overrides ...func(T)) (T, error) {
 	var (
 		config T
 		err    error
@@ -52,27 +80,82 @@ func LoadConfig[T ServiceConfig](fileName string) (T, error) {
 
 	ext := filepath.Ext(fileName)
 
-	switch ext {
-	case ".yml", ".yaml":
+	switch {
+	case fileName == "":
+		config = newConfig[T]()
+	case ext == ".yml", ext == ".yaml":
 		config, err = loadYmlConfig[T](fileName)
-		// log.Println("config", config)
-	case ".json":
+	case ext == ".json":
 		config, err = loadJsonConfig[T](fileName)
 	default:
 		err = fmt.Errorf("invalid config format: %s", fileName)
 	}
 
 	if err != nil {
-		// log.Println("err in switch", err)
 		return config, err
 	}
-	config.GetAppConfig().DbConfig = &DbConfig{ConnectionString: "host=localhost port=5432 user=postgres dbname=iceline password=postgres sslmode=disable"}
+	// an empty file leaves the config nil
+	if reflect.ValueOf(config).IsNil() {
+		config = newConfig[T]()
+	}
+
+	_, err = applyEnv(envPrefix, reflect.ValueOf(config).Elem())
+	if err != nil {
+		return config, err
+	}
+	for _, override := range overrides {
+		override(config)
+	}
 
 	config.GetAppConfig().setDefaults()
-	// maybe parse env
+	if defaulter, ok := any(config).(interface{ SetDefaults() }); ok {
+		defaulter.SetDefaults()
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
 	return config, nil
 }
 
+// newConfig allocates the struct a pointer config type points to
+func newConfig[T ServiceConfig]() T {
+	var config T
+	return reflect.New(reflect.TypeOf(config).Elem()).Interface().(T)
+}
+
+// Validate reports every missing or malformed field of the shared config
+func (c *AppConfig) Validate() error {
+	var problems []string
+
+	if c.DbConfig == nil || strings.TrimSpace(c.DbConfig.ConnectionString) == "" {
+		problems = append(problems, "db_config.connection_string is required")
+	}
+	if c.DbConfig != nil && c.DbConfig.MaxConnRetries < 0 {
+		problems = append(problems, "db_config.max_conn_retries must not be negative")
+	}
+	if c.RedisConfig != nil && c.RedisConfig.URL == "" {
+		problems = append(problems, "redis_config.url is required when redis_config is set")
+	}
+	if !identifierRegex.MatchString(c.AuthorizerTableName) {
+		problems = append(problems, "authorizer_table_name must be a table name")
+	}
+	if c.Auth != nil && c.Auth.JWKSFile != "" {
+		if _, err := os.Stat(c.Auth.JWKSFile); err != nil {
+			problems = append(problems, "auth.jwks_file "+err.Error())
+		}
+	}
+	if c.Probe != nil && c.Probe.Health == c.Probe.Ready {
+		problems = append(problems, "probe.health and probe.ready must differ")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 func (c *AppConfig) setDefaults() {
 	if c.DbConfig != nil {
 		c.DbConfig.setDefaults()
@@ -88,6 +171,22 @@ func (c *AppConfig) setDefaults() {
 	if c.CorsDomains == nil {
 		c.CorsDomains = []string{"*"}
 	}
+	if c.AuthorizerTableName == "" {
+		c.AuthorizerTableName = "api_keys"
+	}
+	if c.Auth == nil {
+		c.Auth = &AuthConfig{}
+	}
+	c.Auth.setDefaults()
+}
+
+func (c *AuthConfig) setDefaults() {
+	if c.UserClaim == "" {
+		c.UserClaim = "sub"
+	}
+	if c.RolesClaim == "" {
+		c.RolesClaim = "roles"
+	}
 }
 
 func (c *DbConfig) setDefaults() {
@@ -97,8 +196,9 @@ func (c *DbConfig) setDefaults() {
 	if c.MaxOpenConns == 0 {
 		c.MaxOpenConns = -1
 	}
-	if c.MigrationsPath == "" {
-		c.MigrationsPath = "./migrations/"
+	if c.AutoMigrate == nil {
+		autoMigrate := true
+		c.AutoMigrate = &autoMigrate
 	}
 }
 
@@ -165,7 +265,7 @@ func loadJsonConfig[T ServiceConfig](fileName string) (T, error) {
 
 	defer file.Close()
 
-	err = json.NewDecoder(file).Decode(config)
+	err = json.NewDecoder(file).Decode(&config)
 	if err != nil {
 		return config, err
 	}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	AppConfig `yaml:",inline"`
+	Port      string `json:"port" yaml:"port" env:"PORT"`
+}
+
+func (c *testConfig) GetAppConfig() *AppConfig {
+	return &c.AppConfig
+}
+
+func (c *testConfig) GetDbConfig() *DbConfig {
+	return c.AppConfig.DbConfig
+}
+
+func (c *testConfig) Validate() error {
+	return c.AppConfig.Validate()
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(file, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	file := writeConfigFile(t, "config.yml", `
+port: "1000"
+domain_name: file.example
+db_config:
+  connection_string: postgres://file
+  max_open_conns: 10
+redis_config:
+  url: redis://file
+probe:
+  prefix: checks
+`)
+	t.Setenv("TEST_PORT", "2000")
+	t.Setenv("TEST_DB_CONNECTION_STRING", "postgres://env")
+	t.Setenv("TEST_DB_AUTO_MIGRATE", "false")
+	t.Setenv("TEST_CORS_DOMAINS", "a.example, ,b.example")
+
+	config, err := LoadConfig(file, "TEST", func(c *testConfig) {
+		c.Port = "3000"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{name: "override beats env", got: config.Port, want: "3000"},
+		{name: "env beats file", got: config.DbConfig.ConnectionString, want: "postgres://env"},
+		{name: "file without env", got: config.DomainName, want: "file.example"},
+		{name: "file field next to env field", got: config.DbConfig.MaxOpenConns, want: 10},
+		{name: "env pointer", got: *config.DbConfig.AutoMigrate, want: false},
+		{name: "env list", got: config.CorsDomains, want: []string{"a.example", "b.example"}},
+		{name: "nested struct from file", got: config.RedisConfig.URL, want: "redis://file"},
+		{name: "default", got: config.DbConfig.MaxConnRetries, want: 5},
+		{name: "default table", got: config.AuthorizerTableName, want: "api_keys"},
+		{name: "normalized prefix", got: config.Probe.Prefix, want: "/checks"},
+		{name: "probe default next to file field", got: config.Probe.Ready, want: "/ready"},
+		{name: "auth claims default", got: config.Auth.UserClaim, want: "sub"},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigFromEnvOnly(t *testing.T) {
+	t.Setenv("TEST_DB_CONNECTION_STRING", "postgres://env")
+
+	config, err := LoadConfig[*testConfig]("", "TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DbConfig == nil || config.DbConfig.ConnectionString != "postgres://env" {
+		t.Fatalf("db config = %+v, want the connection string from the environment", config.DbConfig)
+	}
+	// a nested config without any variable set stays unset
+	if config.RedisConfig != nil {
+		t.Fatalf("redis config = %+v, want nil", config.RedisConfig)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "unknown format", file: "config.toml", want: "invalid config format"},
+		{name: "invalid env int", env: map[string]string{"TEST_DB_CONNECTION_STRING": "x", "TEST_DB_MAX_CONN_RETRIES": "many"}, want: "invalid TEST_DB_MAX_CONN_RETRIES"},
+		{name: "invalid env bool", env: map[string]string{"TEST_DB_CONNECTION_STRING": "x", "TEST_DB_AUTO_MIGRATE": "maybe"}, want: "invalid TEST_DB_AUTO_MIGRATE"},
+		{name: "invalid config", env: map[string]string{"TEST_DB_MAX_CONN_RETRIES": "-1"}, want: "invalid config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			file := tt.file
+			if file != "" {
+				file = writeConfigFile(t, file, "")
+			}
+
+			_, err := LoadConfig[*testConfig](file, "TEST")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppConfigValidate(t *testing.T) {
+	jwks := writeConfigFile(t, "jwks.json", `{"keys":[]}`)
+	valid := func() *AppConfig {
+		c := &AppConfig{DbConfig: &DbConfig{ConnectionString: "postgres://db"}}
+		c.setDefaults()
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *AppConfig)
+		want   []string
+	}{
+		{name: "defaults", modify: func(c *AppConfig) {}},
+		{name: "jwks file", modify: func(c *AppConfig) { c.Auth.JWKSFile = jwks }},
+		{name: "schema qualified table", modify: func(c *AppConfig) { c.AuthorizerTableName = "auth.api_keys" }},
+
+		{name: "missing db config", modify: func(c *AppConfig) { c.DbConfig = nil }, want: []string{"db_config.connection_string is required"}},
+		{name: "blank connection string", modify: func(c *AppConfig) { c.DbConfig.ConnectionString = "  " }, want: []string{"db_config.connection_string is required"}},
+		{name: "negative retries", modify: func(c *AppConfig) { c.DbConfig.MaxConnRetries = -1 }, want: []string{"db_config.max_conn_retries"}},
+		{name: "redis without url", modify: func(c *AppConfig) { c.RedisConfig = &RedisConfig{} }, want: []string{"redis_config.url"}},
+		{name: "table injection", modify: func(c *AppConfig) { c.AuthorizerTableName = "api_keys; drop table gs_info" }, want: []string{"authorizer_table_name"}},
+		{name: "quoted table", modify: func(c *AppConfig) { c.AuthorizerTableName = `"api_keys"` }, want: []string{"authorizer_table_name"}},
+		{name: "missing jwks file", modify: func(c *AppConfig) { c.Auth.JWKSFile = filepath.Join(t.TempDir(), "missing.json") }, want: []string{"auth.jwks_file"}},
+		{name: "same probe paths", modify: func(c *AppConfig) { c.Probe.Ready = c.Probe.Health }, want: []string{"probe.health and probe.ready must differ"}},
+		{
+			name: "every problem",
+			modify: func(c *AppConfig) {
+				c.DbConfig.ConnectionString = ""
+				c.AuthorizerTableName = "1table"
+			},
+			want: []string{"db_config.connection_string", "authorizer_table_name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+
+			err := c.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("error = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides the fields of the struct v points to with environment variables. A field is read from
+// prefix_<env tag>, nested structs extend the prefix with their own tag and embedded structs without a tag
+// share the prefix of their parent. Nil struct pointers are only allocated when one of their variables is
+// set. It reports whether any variable was applied.
+func applyEnv(prefix string, v reflect.Value) (bool, error) {
+	applied := false
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup("env")
+		if tag == "-" || (!tagged && !field.Anonymous) {
+			continue
+		}
+		name := prefix
+		if tagged {
+			name = prefix + "_" + tag
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct {
+			target := value
+			if value.IsNil() {
+				target = reflect.New(value.Type().Elem())
+			}
+			ok, err := applyEnv(name, target.Elem())
+			if err != nil {
+				return false, err
+			}
+			if ok && value.IsNil() {
+				value.Set(target)
+			}
+			applied = applied || ok
+			continue
+		}
+		if value.Kind() == reflect.Struct && value.Type() != durationType {
+			ok, err := applyEnv(name, value)
+			if err != nil {
+				return false, err
+			}
+			applied = applied || ok
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		err := setValue(value, raw)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		applied = true
+	}
+
+	return applied, nil
+}
+
+// setValue parses raw into a string, number, bool, duration or comma separated string list field, or a
+// pointer to one of them
+func setValue(value reflect.Value, raw string) error {
+	if value.Kind() == reflect.Ptr {
+		target := reflect.New(value.Type().Elem())
+		err := setValue(target.Elem(), raw)
+		if err != nil {
+			return err
+		}
+		value.Set(target)
+		return nil
+	}
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", value.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items).Convert(value.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
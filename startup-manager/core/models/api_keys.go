@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKey is a long lived bearer token of a user, only the sha256 hash of the key is stored
+type APIKey struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	UserID     string         `db:"user_id" json:"user_id"`
+	Name       string         `db:"name" json:"name"`
+	Roles      pq.StringArray `db:"roles" json:"roles"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at"`
+}
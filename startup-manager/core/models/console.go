@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsoleSession is the audit record of an interactive console opened on a server
+type ConsoleSession struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	ServerID   uuid.UUID  `db:"server_id" json:"server_id"`
+	Command    string     `db:"command" json:"command"`
+	RemoteAddr string     `db:"remote_addr" json:"remote_addr"`
+	BytesIn    int64      `db:"bytes_in" json:"bytes_in"`
+	BytesOut   int64      `db:"bytes_out" json:"bytes_out"`
+	ExitCode   *int       `db:"exit_code" json:"exit_code"`
+	Error      *string    `db:"error" json:"error"`
+	StartedAt  time.Time  `db:"started_at" json:"started_at"`
+	EndedAt    *time.Time `db:"ended_at" json:"ended_at"`
+}
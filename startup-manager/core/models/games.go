@@ -13,15 +13,23 @@ type Game struct {
 	Image                 string         `db:"image" json:"image"`
 	Envs                  pq.StringArray `db:"envs" json:"envs"`
 	Ports                 pq.Int32Array  `db:"ports" json:"ports"`
+	PortLabels            pq.StringArray `db:"port_labels" json:"port_labels"`
 	Volumes               pq.StringArray `db:"volumes" json:"volumes"`
 	CPU                   int            `db:"cpu" json:"cpu"`
 	Memory                int            `db:"memory" json:"memory"`
+	Disk                  int            `db:"disk" json:"disk"`
 	Command               string         `db:"command" json:"command"`
 	Args                  pq.StringArray `db:"args" json:"args"`
 	DefaultStartupCommand string         `db:"default_startup_command" json:"default_startup_command"`
 	DefaultVariables      pq.StringArray `db:"default_variables" json:"default_variables"`
+	Variables             VariableSchema `db:"variables" json:"variables"`
 	InstallationScript    string         `db:"installation_script" json:"installation_script"`
 	WithDB                bool           `db:"with_db" json:"with_db"`
+	JobTemplate           string         `db:"job_template" json:"job_template"`
+	JobTemplateVersion    int            `db:"job_template_version" json:"job_template_version"`
+	ConsoleCommands       pq.StringArray `db:"console_commands" json:"console_commands"`
+	ConnectTemplate       string         `db:"connect_template" json:"connect_template"`
 	CreatedAt             time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt             *time.Time     `db:"updated_at" json:"updated_at"`
+	DeletedAt             *time.Time     `db:"deleted_at" json:"-"`
 }
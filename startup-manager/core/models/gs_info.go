@@ -1,16 +1,37 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Game server states persisted in gs_info.status
+const (
+	ServerStatusCreated    = "created"
+	ServerStatusInstalling = "installing"
+	ServerStatusStarting   = "starting"
+	ServerStatusRunning    = "running"
+	ServerStatusRestarting = "restarting"
+	ServerStatusCrashed    = "crashed"
+	ServerStatusStopped    = "stopped"
+	ServerStatusDeleted    = "deleted"
+)
 
 type GameServerInfo struct {
-	ID         string     `db:"id"`
-	UserID     string     `db:"user_id"`
-	ServerName string     `db:"server_name"`
-	GameName   string     `db:"game_name"`
-	Image      string     `db:"image"`
-	Command    string     `db:"command"`
-	Status     string     `db:"status"`
-	CreatedAt  *time.Time `db:"created_at"`
-	UpdatedAt  *time.Time `db:"updated_at"`
-	DeletedAt  *time.Time `db:"deleted_at"`
+	ID              string     `db:"id" json:"id"`
+	UserID          string     `db:"user_id" json:"user_id"`
+	ServerName      string     `db:"server_name" json:"server_name"`
+	GameName        string     `db:"game_name" json:"game_name"`
+	Image           string     `db:"image" json:"image"`
+	Command         string     `db:"command" json:"command"`
+	JobID           string     `db:"job_id" json:"job_id"`
+	Namespace       string     `db:"namespace" json:"namespace"`
+	Status          string     `db:"status" json:"status"`
+	ActiveStartupID *uuid.UUID `db:"active_startup_id" json:"active_startup_id"`
+	CPU             *int       `db:"cpu" json:"cpu"`
+	Memory          *int       `db:"memory" json:"memory"`
+	CreatedAt       *time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt       *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operation states
+const (
+	OperationStateQueued    = "queued"
+	OperationStateRunning   = "running"
+	OperationStateSucceeded = "succeeded"
+	OperationStateFailed    = "failed"
+	OperationStateCancelled = "cancelled"
+)
+
+// Operation is a long running change requested by a client, it is processed by the operation workers
+// and polled or streamed until it reaches a final state
+type Operation struct {
+	ID         uuid.UUID         `db:"id" json:"id"`
+	Kind       string            `db:"kind" json:"kind"`
+	ServerID   uuid.UUID         `db:"server_id" json:"server_id"`
+	State      string            `db:"state" json:"state"`
+	Messages   OperationMessages `db:"messages" json:"messages"`
+	Result     OperationResult   `db:"result" json:"result"`
+	Error      *string           `db:"error" json:"error"`
+	CreatedAt  time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time         `db:"updated_at" json:"updated_at"`
+	StartedAt  *time.Time        `db:"started_at" json:"started_at"`
+	FinishedAt *time.Time        `db:"finished_at" json:"finished_at"`
+}
+
+// Done reports whether the operation reached a final state
+func (o *Operation) Done() bool {
+	switch o.State {
+	case OperationStateSucceeded, OperationStateFailed, OperationStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// OperationMessage is a progress message of an operation
+type OperationMessage struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// OperationMessages is stored as a JSONB array
+type OperationMessages []OperationMessage
+
+func (m OperationMessages) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *OperationMessages) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
+
+// OperationResult is the outcome of a succeeded operation, stored as JSONB
+type OperationResult map[string]interface{}
+
+func (r OperationResult) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *OperationResult) Scan(value interface{}) error {
+	return scanJSON(value, r)
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of outbox entries, every entry is the work of the operation of the same kind
+const (
+	// OutboxKindApplyStartup registers the job spec of a startup revision with nomad
+	OutboxKindApplyStartup  = "apply_startup"
+	OutboxKindStartServer   = "start_server"
+	OutboxKindStopServer    = "stop_server"
+	OutboxKindRestartServer = "restart_server"
+	OutboxKindDeleteServer  = "delete_server"
+)
+
+// OutboxEntry is a change stored together with the database write it belongs to, the dispatcher applies
+// it to nomad afterwards
+type OutboxEntry struct {
+	ID            uuid.UUID  `db:"id" json:"id"`
+	Kind          string     `db:"kind" json:"kind"`
+	ServerID      uuid.UUID  `db:"server_id" json:"server_id"`
+	StartupID     *uuid.UUID `db:"startup_id" json:"startup_id"`
+	OperationID   *uuid.UUID `db:"operation_id" json:"operation_id"`
+	Attempts      int        `db:"attempts" json:"attempts"`
+	LastError     *string    `db:"last_error" json:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" json:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	ProcessedAt   *time.Time `db:"processed_at" json:"processed_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlanName is the plan of users without a user_quotas row
+const DefaultPlanName = "default"
+
+// Plan bounds the total resources of a user's servers and the cpu and memory of each single server, cpu in
+// MHz and memory and disk in MB
+type Plan struct {
+	ID              uuid.UUID  `db:"id" json:"id"`
+	Name            string     `db:"name" json:"name"`
+	MaxServers      int        `db:"max_servers" json:"max_servers"`
+	MaxCPU          int        `db:"max_cpu" json:"max_cpu"`
+	MaxMemory       int        `db:"max_memory" json:"max_memory"`
+	MaxDisk         int        `db:"max_disk" json:"max_disk"`
+	MaxServerCPU    int        `db:"max_server_cpu" json:"max_server_cpu"`
+	MaxServerMemory int        `db:"max_server_memory" json:"max_server_memory"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       *time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ServerResources are what a server's job reserves, cpu in MHz and memory and disk in MB
+type ServerResources struct {
+	CPU    int `db:"cpu" json:"cpu"`
+	Memory int `db:"memory" json:"memory"`
+	Disk   int `db:"disk" json:"disk"`
+}
+
+// QuotaLimits are the limits of a user, the plan's unless the user has overrides
+type QuotaLimits struct {
+	Servers int `db:"max_servers" json:"servers"`
+	CPU     int `db:"max_cpu" json:"cpu"`
+	Memory  int `db:"max_memory" json:"memory"`
+	Disk    int `db:"max_disk" json:"disk"`
+	// ServerCPU and ServerMemory are the most a single server may be given
+	ServerCPU    int `db:"max_server_cpu" json:"server_cpu"`
+	ServerMemory int `db:"max_server_memory" json:"server_memory"`
+}
+
+// UserQuota is the plan of a user and the limits that apply to them
+type UserQuota struct {
+	UserID string `db:"user_id" json:"user_id"`
+	Plan   string `db:"plan_name" json:"plan"`
+	QuotaLimits
+}
+
+// QuotaUsage counts a user's servers and the resources reserved by the ones holding a nomad job
+type QuotaUsage struct {
+	Servers int `db:"servers" json:"servers"`
+	ServerResources
+}
@@ -7,15 +7,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// Apply states of a startup revision
+const (
+	StartupApplyPending    = "pending"
+	StartupApplyApplied    = "applied"
+	StartupApplyFailed     = "failed"
+	StartupApplySuperseded = "superseded"
+)
+
 // StartupInfo represents the startups_info table in the database.
 type StartupInfo struct {
-	ID            	uuid.UUID              `json:"id" db:"id"`
-	ServerID      	uuid.UUID              `json:"server_id" db:"server_id"`
-	Variables     	map[string]interface{} `json:"variables" db:"variables"`
-	StartupCommand  string					`json:"startup_command" db:"startup_command`
-	CreatedAt     	time.Time              `json:"created_at" db:"created_at"`
-	UpdatedAt     	*time.Time              `json:"updated_at" db:"updated_at"`
-	DeletedAt     	*time.Time              `json:"deleted_at" db:"deleted_at"`
+	ID             uuid.UUID              `json:"id" db:"id"`
+	ServerID       uuid.UUID              `json:"server_id" db:"server_id"`
+	Revision       int                    `json:"revision" db:"revision"`
+	Variables      map[string]interface{} `json:"variables" db:"variables"`
+	StartupCommand string                 `json:"startup_command" db:"command"`
+	JobSpec        string                 `json:"job_spec" db:"job_spec"`
+	CPU            *int                   `json:"cpu" db:"cpu"`
+	Memory         *int                   `json:"memory" db:"memory"`
+	RollbackOf     *int                   `json:"rollback_of,omitempty" db:"rollback_of"`
+	ApplyStatus    string                 `json:"apply_status" db:"apply_status"`
+	ApplyError     *string                `json:"apply_error,omitempty" db:"apply_error"`
+	AppliedAt      *time.Time             `json:"applied_at,omitempty" db:"applied_at"`
+	CreatedAt      time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt      *time.Time             `json:"updated_at" db:"updated_at"`
+	DeletedAt      *time.Time             `json:"deleted_at" db:"deleted_at"`
 }
 
 // JSONB represents a JSONB data type.
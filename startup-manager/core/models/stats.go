@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resolutions of the samples in server_stats, raw samples are rolled up into the coarser ones
+const (
+	StatsResolutionRaw        = "raw"
+	StatsResolutionFiveMinute = "5m"
+	StatsResolutionHour       = "1h"
+)
+
+// ServerStatsSample is the resource usage of a server in one bucket, raw samples hold a single
+// measurement, rolled up samples the average and maximum of the samples they cover
+type ServerStatsSample struct {
+	ServerID             uuid.UUID `db:"server_id" json:"-"`
+	Resolution           string    `db:"resolution" json:"-"`
+	Bucket               time.Time `db:"bucket" json:"time"`
+	CPUPercent           float64   `db:"cpu_percent" json:"cpu_percent"`
+	CPUPercentMax        float64   `db:"cpu_percent_max" json:"cpu_percent_max"`
+	MemoryBytes          int64     `db:"memory_bytes" json:"memory_bytes"`
+	MemoryBytesMax       int64     `db:"memory_bytes_max" json:"memory_bytes_max"`
+	MemoryLimitBytes     int64     `db:"memory_limit_bytes" json:"memory_limit_bytes"`
+	NetworkReservedMBits int       `db:"network_reserved_mbits" json:"network_reserved_mbits"`
+	Samples              int       `db:"samples" json:"samples"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Types a startup variable can be declared with
+const (
+	VariableTypeInt    = "int"
+	VariableTypeBool   = "bool"
+	VariableTypeEnum   = "enum"
+	VariableTypeString = "string"
+)
+
+// VariableSpec declares a startup variable of a game. Min and Max bound int values
+// and the length of string values, Regex must match string values as a whole.
+type VariableSpec struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Default      string   `json:"default"`
+	Description  string   `json:"description"`
+	Min          *int64   `json:"min,omitempty"`
+	Max          *int64   `json:"max,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Options      []string `json:"options,omitempty"`
+	UserEditable bool     `json:"user_editable"`
+}
+
+// VariableSchema is the list of startup variables stored in games.variables
+type VariableSchema []VariableSpec
+
+func (s VariableSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *VariableSchema) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = VariableSchema{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return errors.New("unsupported type for variable schema")
+	}
+}
+
+// Lookup returns the spec of the named variable
+func (s VariableSchema) Lookup(name string) (VariableSpec, bool) {
+	for _, spec := range s {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return VariableSpec{}, false
+}
+
+// Schema returns the game's variable schema. Games without one get editable string
+// variables built from default_variables.
+func (g *Game) Schema() VariableSchema {
+	if len(g.Variables) > 0 {
+		return g.Variables
+	}
+
+	schema := make(VariableSchema, 0, len(g.DefaultVariables))
+	for _, v := range g.DefaultVariables {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		schema = append(schema, VariableSpec{
+			Name:         parts[0],
+			Type:         VariableTypeString,
+			Default:      strings.Trim(parts[1], "\""),
+			UserEditable: true,
+		})
+	}
+	return schema
+}
@@ -0,0 +1,349 @@
+package nomadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+// What RegisterJob waits for and the outcomes of waiting
+const (
+	DeployPlaced   = "placed"
+	DeployRunning  = "running"
+	DeployFailed   = "failed"
+	DeployTimedOut = "timed_out"
+)
+
+const (
+	taskStateRunning = "running"
+
+	defaultDeployTimeout     = 5 * time.Minute
+	defaultDeployMaxRestarts = 2
+	deployPollTime           = 10 * time.Second
+)
+
+// RegisterOptions control whether RegisterJob waits for the registered job to come up
+type RegisterOptions struct {
+	// Wait is DeployPlaced or DeployRunning to block until the job's allocations reach that state,
+	// empty returns as soon as the job is registered
+	Wait string
+	// Timeout bounds the wait, it defaults to 5 minutes
+	Timeout time.Duration
+	// MaxRestarts is how often a task may restart while waiting before the job counts as crash looping
+	MaxRestarts int
+	// AutoRevert reverts the job to the version it ran before when the new version fails
+	AutoRevert bool
+	// Progress is called with a message whenever the state of the evaluation, deployment or allocations changes
+	Progress func(message string)
+}
+
+// RegisterResult describes a registration and, when RegisterJob waited, how it ended
+type RegisterResult struct {
+	EvalID       string  `json:"eval_id"`
+	DeploymentID string  `json:"deployment_id,omitempty"`
+	JobVersion   uint64  `json:"job_version"`
+	Status       string  `json:"status,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+	Warnings     string  `json:"warnings,omitempty"`
+	RevertedTo   *uint64 `json:"reverted_to,omitempty"`
+}
+
+// RegisterJob registers the job HCL in its namespace. With opts.Wait set it follows the evaluation, the
+// deployment and the allocations of the new job version until they are placed or running, fail or the
+// timeout passes. A failed version is reverted to the previous one when opts.AutoRevert is set.
+func (n *NomadClient) RegisterJob(ctx context.Context, jobHCL string, opts RegisterOptions) (*RegisterResult, error) {
+	job, err := n.client.Jobs().ParseHCL(jobHCL, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse job hcl: %w", err)
+	}
+	namespace := *job.Namespace
+
+	_, err = n.client.Namespaces().Register(&nomadApi.Namespace{Name: namespace}, (&nomadApi.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not register namespace: %w", err)
+	}
+
+	// the version that ran before this registration, it is what a failed registration reverts to
+	var previous *uint64
+	if opts.Wait != "" && opts.AutoRevert {
+		current, _, err := n.client.Jobs().Info(*job.ID, (&nomadApi.QueryOptions{Namespace: namespace}).WithContext(ctx))
+		if err != nil && !IsNotFound(err) {
+			return nil, fmt.Errorf("could not read job: %w", err)
+		}
+		if err == nil && current.Version != nil && (current.Stop == nil || !*current.Stop) {
+			version := *current.Version
+			previous = &version
+		}
+	}
+
+	resp, _, err := n.client.Jobs().Register(job, (&nomadApi.WriteOptions{Namespace: namespace}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not register job: %w", err)
+	}
+
+	result := &RegisterResult{EvalID: resp.EvalID, Warnings: resp.Warnings}
+	if opts.Wait == "" {
+		return result, nil
+	}
+
+	// the version is read before waiting, a failed evaluation has to know what it reverts from
+	registered, _, err := n.client.Jobs().Info(*job.ID, (&nomadApi.QueryOptions{Namespace: namespace}).WithContext(ctx))
+	if err != nil {
+		return result, fmt.Errorf("could not read job: %w", err)
+	}
+	result.JobVersion = *registered.Version
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultDeployTimeout
+	}
+	if opts.MaxRestarts <= 0 {
+		opts.MaxRestarts = defaultDeployMaxRestarts
+	}
+	if opts.Progress == nil {
+		opts.Progress = func(string) {}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = n.waitForJob(waitCtx, *job.ID, namespace, opts, result)
+	if err != nil {
+		if ctx.Err() != nil || !errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			return result, err
+		}
+		result.Status = DeployTimedOut
+		result.Reason = fmt.Sprintf("job did not become %s within %s", opts.Wait, timeout)
+		opts.Progress(result.Reason)
+	}
+
+	if result.Status == DeployFailed && opts.AutoRevert && previous != nil && *previous != result.JobVersion {
+		opts.Progress(fmt.Sprintf("reverting job to version %d", *previous))
+		_, _, err = n.client.Jobs().Revert(*job.ID, *previous, &result.JobVersion,
+			(&nomadApi.WriteOptions{Namespace: namespace}).WithContext(ctx), "", "")
+		if err != nil {
+			return result, fmt.Errorf("could not revert job to version %d: %w", *previous, err)
+		}
+		result.RevertedTo = previous
+	}
+
+	return result, nil
+}
+
+func (n *NomadClient) waitForJob(ctx context.Context, jobID, namespace string, opts RegisterOptions, result *RegisterResult) error {
+	eval, err := n.waitForEvaluation(ctx, namespace, result.EvalID, opts.Progress)
+	if err != nil {
+		return err
+	}
+	result.DeploymentID = eval.DeploymentID
+
+	switch {
+	case eval.Status != nomadApi.EvalStatusComplete:
+		result.Status = DeployFailed
+		result.Reason = fmt.Sprintf("evaluation %s: %s", eval.Status, eval.StatusDescription)
+		return nil
+	case len(eval.FailedTGAllocs) > 0:
+		result.Status = DeployFailed
+		result.Reason = placementFailure(eval.FailedTGAllocs)
+		return nil
+	}
+
+	var (
+		index    uint64
+		progress string
+	)
+	for {
+		q := (&nomadApi.QueryOptions{Namespace: namespace, WaitIndex: index, WaitTime: deployPollTime}).WithContext(ctx)
+		allocs, meta, err := n.client.Jobs().Allocations(jobID, false, q)
+		if err != nil {
+			return fmt.Errorf("could not list allocations: %w", err)
+		}
+		index = meta.LastIndex
+
+		var deployment *nomadApi.Deployment
+		if result.DeploymentID != "" {
+			deployment, _, err = n.client.Deployments().Info(result.DeploymentID, (&nomadApi.QueryOptions{Namespace: namespace}).WithContext(ctx))
+			if err != nil {
+				return fmt.Errorf("could not read deployment: %w", err)
+			}
+		}
+
+		state := jobState(allocs, deployment, result.JobVersion, opts)
+		if state.summary != progress {
+			progress = state.summary
+			opts.Progress(progress)
+		}
+		if state.status != "" {
+			result.Status = state.status
+			result.Reason = state.reason
+			return nil
+		}
+	}
+}
+
+func (n *NomadClient) waitForEvaluation(ctx context.Context, namespace, evalID string, progress func(string)) (*nomadApi.Evaluation, error) {
+	var (
+		index  uint64
+		status string
+	)
+	for {
+		q := (&nomadApi.QueryOptions{Namespace: namespace, WaitIndex: index, WaitTime: deployPollTime}).WithContext(ctx)
+		eval, meta, err := n.client.Evaluations().Info(evalID, q)
+		if err != nil {
+			return nil, fmt.Errorf("could not read evaluation: %w", err)
+		}
+		index = meta.LastIndex
+
+		if eval.Status != status {
+			status = eval.Status
+			progress(fmt.Sprintf("evaluation %s", status))
+		}
+
+		switch eval.Status {
+		case nomadApi.EvalStatusComplete, nomadApi.EvalStatusFailed, nomadApi.EvalStatusCancelled:
+			return eval, nil
+		}
+	}
+}
+
+type deployState struct {
+	// status is set once waiting is over
+	status  string
+	reason  string
+	summary string
+}
+
+// jobState looks at the allocations of the job version, and its deployment if it has one, and decides
+// whether waiting is over
+func jobState(allocs []*nomadApi.AllocationListStub, deployment *nomadApi.Deployment, version uint64, opts RegisterOptions) deployState {
+	counts := map[string]int{}
+	placed, running := 0, 0
+
+	for _, alloc := range allocs {
+		if alloc.JobVersion != version || alloc.DesiredStatus != nomadApi.AllocDesiredStatusRun {
+			continue
+		}
+		placed++
+		counts[alloc.ClientStatus]++
+
+		if alloc.ClientStatus == nomadApi.AllocClientStatusFailed {
+			return deployState{status: DeployFailed, reason: allocFailure(alloc), summary: "allocation failed"}
+		}
+		for task, state := range alloc.TaskStates {
+			if state.Restarts >= uint64(opts.MaxRestarts) {
+				return deployState{
+					status:  DeployFailed,
+					reason:  fmt.Sprintf("task %s restarted %d times: %s", task, state.Restarts, lastTaskEvent(state)),
+					summary: "allocation is crash looping",
+				}
+			}
+		}
+		if allocRunning(alloc) {
+			running++
+		}
+	}
+
+	summary := allocSummary(counts)
+	if deployment != nil {
+		summary = fmt.Sprintf("deployment %s, %s", deployment.Status, summary)
+		switch deployment.Status {
+		case nomadApi.DeploymentStatusFailed, nomadApi.DeploymentStatusCancelled:
+			return deployState{status: DeployFailed, reason: "deployment " + deployment.Status + ": " + deployment.StatusDescription, summary: summary}
+		}
+	}
+
+	switch {
+	case placed == 0:
+		return deployState{summary: summary}
+	case opts.Wait == DeployPlaced:
+		return deployState{status: DeployPlaced, summary: summary}
+	case deployment != nil:
+		if deployment.Status == nomadApi.DeploymentStatusSuccessful {
+			return deployState{status: DeployRunning, summary: summary}
+		}
+	case running == placed:
+		return deployState{status: DeployRunning, summary: summary}
+	}
+	return deployState{summary: summary}
+}
+
+func allocRunning(alloc *nomadApi.AllocationListStub) bool {
+	if alloc.ClientStatus != nomadApi.AllocClientStatusRunning {
+		return false
+	}
+	for _, state := range alloc.TaskStates {
+		if state.State != taskStateRunning {
+			return false
+		}
+	}
+	return true
+}
+
+func allocSummary(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "no allocations placed yet"
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for status, count := range counts {
+		statuses = append(statuses, fmt.Sprintf("%d %s", count, status))
+	}
+	sort.Strings(statuses)
+	return "allocations " + strings.Join(statuses, ", ")
+}
+
+func allocFailure(alloc *nomadApi.AllocationListStub) string {
+	for task, state := range alloc.TaskStates {
+		if state.Failed {
+			return fmt.Sprintf("task %s failed: %s", task, lastTaskEvent(state))
+		}
+	}
+	if alloc.ClientDescription != "" {
+		return alloc.ClientDescription
+	}
+	return "allocation failed"
+}
+
+// lastTaskEvent returns the message of the task's newest event
+func lastTaskEvent(state *nomadApi.TaskState) string {
+	if len(state.Events) == 0 {
+		return state.State
+	}
+
+	event := state.Events[len(state.Events)-1]
+	if event.DisplayMessage != "" {
+		return event.Type + ": " + event.DisplayMessage
+	}
+	return event.Type
+}
+
+// PlanPlacementFailure describes why task groups of the plan could not be placed, it is empty when nomad
+// can place all of them or there is no plan
+func PlanPlacementFailure(plan *JobPlan) string {
+	if plan == nil {
+		return ""
+	}
+	return placementFailure(plan.FailedTGAllocs)
+}
+
+// placementFailure explains why task groups could not be placed
+func placementFailure(failed map[string]*nomadApi.AllocationMetric) string {
+	reasons := make([]string, 0, len(failed))
+	for group, metric := range failed {
+		reason := fmt.Sprintf("group %s could not be placed, %d of %d evaluated nodes passed filtering", group, metric.NodesEvaluated-metric.NodesFiltered, metric.NodesEvaluated)
+		for dimension, count := range metric.DimensionExhausted {
+			reason += fmt.Sprintf(", %s exhausted on %d", dimension, count)
+		}
+		for constraint, count := range metric.ConstraintFiltered {
+			reason += fmt.Sprintf(", %q filtered %d", constraint, count)
+		}
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,102 @@
+package nomadapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+// fakeNomad answers the endpoints RegisterJob uses for a job that runs at version 3 and fails to place at
+// version 4
+type fakeNomad struct {
+	mu      sync.Mutex
+	version uint64
+	revert  *nomadApi.JobRevertRequest
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	jobID, namespace := "gs-1", "gs-1"
+	switch r.URL.Path {
+	case "/v1/jobs/parse":
+		writeJSON(w, &nomadApi.Job{ID: &jobID, Name: &jobID, Namespace: &namespace})
+	case "/v1/namespace":
+		writeJSON(w, struct{}{})
+	case "/v1/jobs":
+		f.version++
+		writeJSON(w, &nomadApi.JobRegisterResponse{EvalID: "eval-1"})
+	case "/v1/job/gs-1":
+		version := f.version
+		writeJSON(w, &nomadApi.Job{ID: &jobID, Namespace: &namespace, Version: &version})
+	case "/v1/evaluation/eval-1":
+		writeJSON(w, &nomadApi.Evaluation{
+			ID:     "eval-1",
+			Status: nomadApi.EvalStatusComplete,
+			FailedTGAllocs: map[string]*nomadApi.AllocationMetric{
+				"game": {NodesEvaluated: 2, NodesFiltered: 2},
+			},
+		})
+	case "/v1/job/gs-1/revert":
+		f.revert = &nomadApi.JobRevertRequest{}
+		if err := json.NewDecoder(r.Body).Decode(f.revert); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f.revert.EnforcePriorVersion == nil || *f.revert.EnforcePriorVersion != f.version {
+			http.Error(w, "enforcing version does not match current version", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, &nomadApi.JobRegisterResponse{EvalID: "eval-2"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Nomad-Index", "1")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestRegisterJobRevertsFailedPlacement(t *testing.T) {
+	fake := &fakeNomad{version: 3}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	nc, err := nomadApi.NewClient(&nomadApi.Config{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &NomadClient{client: nc}
+
+	result, err := client.RegisterJob(context.Background(), `job "gs-1" {}`, RegisterOptions{
+		Wait:       DeployRunning,
+		AutoRevert: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != DeployFailed {
+		t.Fatalf("status = %q, want %q", result.Status, DeployFailed)
+	}
+	if result.JobVersion != 4 {
+		t.Fatalf("job version = %d, want 4", result.JobVersion)
+	}
+	if fake.revert == nil {
+		t.Fatal("job was not reverted")
+	}
+	if fake.revert.JobVersion != 3 {
+		t.Fatalf("reverted to version %d, want 3", fake.revert.JobVersion)
+	}
+	if result.RevertedTo == nil || *result.RevertedTo != 3 {
+		t.Fatalf("reverted to = %v, want 3", result.RevertedTo)
+	}
+}
@@ -0,0 +1,105 @@
+package nomadapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+// ServerJob is a registered job in one of the game server namespaces
+type ServerJob struct {
+	ID        string
+	Namespace string
+	Status    string
+	Stop      bool
+}
+
+// ListServerJobs returns the jobs of all game server namespaces
+func (n *NomadClient) ListServerJobs(ctx context.Context) ([]ServerJob, error) {
+	stubs, _, err := n.client.Jobs().List((&nomadApi.QueryOptions{Namespace: "*", Prefix: serverPrefix}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	jobs := make([]ServerJob, 0, len(stubs))
+	for _, stub := range stubs {
+		if !IsServerNamespace(stub.Namespace) {
+			continue
+		}
+		jobs = append(jobs, ServerJob{
+			ID:        stub.ID,
+			Namespace: stub.Namespace,
+			Status:    stub.Status,
+			Stop:      stub.Stop,
+		})
+	}
+	return jobs, nil
+}
+
+// ListServerNamespaces returns the names of all game server namespaces
+func (n *NomadClient) ListServerNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, _, err := n.client.Namespaces().PrefixList(serverPrefix, (&nomadApi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not list namespaces: %w", err)
+	}
+
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		names = append(names, namespace.Name)
+	}
+	return names, nil
+}
+
+// DeleteNamespace removes a namespace, nomad refuses while it still holds jobs
+func (n *NomadClient) DeleteNamespace(ctx context.Context, namespace string) error {
+	_, err := n.client.Namespaces().Delete(namespace, (&nomadApi.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("could not delete namespace: %w", err)
+	}
+	return nil
+}
+
+// DiffSummary flattens a job diff into one line per changed field, prefixed with the path of the changed
+// task group, task or block
+func DiffSummary(diff *nomadApi.JobDiff) []string {
+	if diff == nil {
+		return nil
+	}
+
+	var lines []string
+	summarizeDiff(&lines, "job", diff.Fields, diff.Objects)
+	for _, group := range diff.TaskGroups {
+		path := "group " + group.Name
+		if group.Type == "Added" || group.Type == "Deleted" {
+			lines = append(lines, fmt.Sprintf("%s %s", strings.ToLower(group.Type), path))
+			continue
+		}
+		summarizeDiff(&lines, path, group.Fields, group.Objects)
+		for _, task := range group.Tasks {
+			taskPath := path + " / task " + task.Name
+			if task.Type == "Added" || task.Type == "Deleted" {
+				lines = append(lines, fmt.Sprintf("%s %s", strings.ToLower(task.Type), taskPath))
+				continue
+			}
+			summarizeDiff(&lines, taskPath, task.Fields, task.Objects)
+		}
+	}
+	return lines
+}
+
+func summarizeDiff(lines *[]string, path string, fields []*nomadApi.FieldDiff, objects []*nomadApi.ObjectDiff) {
+	for _, field := range fields {
+		if field.Type == "None" {
+			continue
+		}
+		*lines = append(*lines, fmt.Sprintf("%s / %s: %q => %q", path, field.Name, field.Old, field.New))
+	}
+	for _, object := range objects {
+		if object.Type == "None" {
+			continue
+		}
+		summarizeDiff(lines, path+" / "+object.Name, object.Fields, object.Objects)
+	}
+}
@@ -0,0 +1,67 @@
+package nomadapi
+
+import (
+	"reflect"
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+func TestDiffSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		diff *nomadApi.JobDiff
+		want []string
+	}{
+		{name: "no diff", diff: nil, want: nil},
+		{name: "unchanged", diff: &nomadApi.JobDiff{Type: "None", Fields: []*nomadApi.FieldDiff{{Type: "None", Name: "Priority", Old: "50", New: "50"}}}, want: nil},
+		{
+			name: "job field",
+			diff: &nomadApi.JobDiff{Type: "Edited", Fields: []*nomadApi.FieldDiff{{Type: "Edited", Name: "Priority", Old: "50", New: "70"}}},
+			want: []string{`job / Priority: "50" => "70"`},
+		},
+		{
+			name: "added and deleted groups",
+			diff: &nomadApi.JobDiff{Type: "Edited", TaskGroups: []*nomadApi.TaskGroupDiff{
+				{Type: "Added", Name: "voice"},
+				{Type: "Deleted", Name: "web"},
+			}},
+			want: []string{"added group voice", "deleted group web"},
+		},
+		{
+			name: "nested task objects",
+			diff: &nomadApi.JobDiff{Type: "Edited", TaskGroups: []*nomadApi.TaskGroupDiff{{
+				Type:   "Edited",
+				Name:   "game",
+				Fields: []*nomadApi.FieldDiff{{Type: "Edited", Name: "Count", Old: "1", New: "2"}},
+				Tasks: []*nomadApi.TaskDiff{
+					{
+						Type: "Edited",
+						Name: "server",
+						Objects: []*nomadApi.ObjectDiff{{
+							Type:    "Edited",
+							Name:    "Resources",
+							Fields:  []*nomadApi.FieldDiff{{Type: "Edited", Name: "CPU", Old: "1000", New: "2000"}, {Type: "None", Name: "MemoryMB", Old: "2048", New: "2048"}},
+							Objects: []*nomadApi.ObjectDiff{{Type: "None", Name: "Network"}},
+						}},
+					},
+					{Type: "Added", Name: "sidecar"},
+				},
+			}}},
+			want: []string{
+				`group game / Count: "1" => "2"`,
+				`group game / task server / Resources / CPU: "1000" => "2000"`,
+				"added group game / task sidecar",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffSummary(tt.diff)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DiffSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,14 @@ package nomadapi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
+	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	nomadApi "github.com/hashicorp/nomad/api"
 )
@@ -20,6 +21,46 @@ const (
 	stderrLogType = "stderr"
 )
 
+// Log streams that can be passed to GetLogs and StreamLogs
+const (
+	LogTypeStdout = stdoutLogType
+	LogTypeStderr = stderrLogType
+)
+
+// TerminalSize is the size of the terminal attached to a RunCommand session
+type TerminalSize = nomadApi.TerminalSize
+
+// JobPlan is the result of a dry-run job plan, including the diff against the registered job
+type JobPlan = nomadApi.JobPlanResponse
+
+// serverPrefix starts the job ids and namespaces of game servers
+const serverPrefix = "gs-"
+
+// JobIDForServer derives the nomad job id of a game server from its gs_info id
+func JobIDForServer(serverID string) string {
+	return serverPrefix + serverID
+}
+
+// NamespaceForServer derives the nomad namespace of a game server from its gs_info id
+func NamespaceForServer(serverID string) string {
+	return serverPrefix + serverID
+}
+
+// IsServerNamespace reports whether the namespace is one this service manages for a game server
+func IsServerNamespace(namespace string) bool {
+	return strings.HasPrefix(namespace, serverPrefix)
+}
+
+// IsNotFound reports whether err is a 404 returned by the nomad api
+func IsNotFound(err error) bool {
+	var respErr nomadApi.UnexpectedResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode() == http.StatusNotFound
+	}
+
+	return false
+}
+
 type NomadClient struct {
 	client *nomadApi.Client
 }
@@ -44,23 +85,54 @@ func NewNomadClient(nomadURL string) (*NomadClient, error) {
 	}, nil
 }
 
-func (n *NomadClient) RegisterJob(ctx context.Context, jobHCL string) error {
-	job, err := n.client.Jobs().ParseHCL(jobHCL, true)
+// StreamEvents subscribes to the job, allocation and deployment events of all namespaces, starting at index.
+// The channel is closed when ctx is cancelled or the stream breaks, which is reported in Events.Err.
+func (n *NomadClient) StreamEvents(ctx context.Context, index uint64) (<-chan *nomadApi.Events, error) {
+	topics := map[nomadApi.Topic][]string{
+		nomadApi.TopicJob:        {"*"},
+		nomadApi.TopicAllocation: {"*"},
+		nomadApi.TopicDeployment: {"*"},
+	}
+
+	events, err := n.client.EventStream().Stream(ctx, topics, index, &nomadApi.QueryOptions{Namespace: "*"})
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to events: %w", err)
+	}
+	return events, nil
+}
+
+// ValidateJob parses the job HCL on the nomad server without registering it
+func (n *NomadClient) ValidateJob(ctx context.Context, jobHCL string) error {
+	_, err := n.client.Jobs().ParseHCL(jobHCL, true)
 	if err != nil {
 		return fmt.Errorf("could not parse job hcl: %w", err)
 	}
 
-	_, err = n.client.Namespaces().Register(&nomadApi.Namespace{Name: *job.Namespace}, &nomadApi.WriteOptions{})
+	return nil
+}
+
+// PlanJob runs a dry-run plan of the job HCL against the registered job without changing anything.
+// A nil plan is returned when the job's namespace does not exist yet, nothing was ever registered.
+func (n *NomadClient) PlanJob(ctx context.Context, jobHCL string) (*JobPlan, error) {
+	job, err := n.client.Jobs().ParseHCL(jobHCL, true)
 	if err != nil {
-		return fmt.Errorf("could not register namespace: %w", err)
+		return nil, fmt.Errorf("could not parse job hcl: %w", err)
 	}
 
-	_, _, err = n.client.Jobs().Register(job, &nomadApi.WriteOptions{Namespace: *job.Namespace})
+	_, _, err = n.client.Namespaces().Info(*job.Namespace, (&nomadApi.QueryOptions{}).WithContext(ctx))
+	if IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("could not register job: %w", err)
+		return nil, fmt.Errorf("could not read namespace: %w", err)
 	}
 
-	return nil
+	plan, _, err := n.client.Jobs().Plan(job, true, (&nomadApi.WriteOptions{Namespace: *job.Namespace}).WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not plan job: %w", err)
+	}
+
+	return plan, nil
 }
 
 func (n *NomadClient) CheckJobStatus(ctx context.Context, jobID, namespace string) (string, error) {
@@ -77,9 +149,9 @@ func (n *NomadClient) CheckJobStatus(ctx context.Context, jobID, namespace strin
 	return alloc.ClientStatus, nil
 }
 
-// Write function that starts job and allocation with given ID
-func (n *NomadClient) StartJob(ctx context.Context, jobID string) error {
-	job, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: jobID})
+// StartJob starts a stopped job and its allocations
+func (n *NomadClient) StartJob(ctx context.Context, jobID, namespace string) error {
+	job, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
@@ -90,7 +162,7 @@ func (n *NomadClient) StartJob(ctx context.Context, jobID string) error {
 	_, _, err = n.client.Jobs().RegisterOpts(job, &nomadApi.RegisterOptions{
 		EnforceIndex: true,
 		ModifyIndex:  *job.JobModifyIndex,
-	}, &nomadApi.WriteOptions{Namespace: jobID})
+	}, &nomadApi.WriteOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
@@ -99,13 +171,13 @@ func (n *NomadClient) StartJob(ctx context.Context, jobID string) error {
 }
 
 // StopJob stops the job and all allocations
-func (n *NomadClient) StopJob(ctx context.Context, jobID string) error {
-	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: jobID})
+func (n *NomadClient) StopJob(ctx context.Context, jobID, namespace string) error {
+	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
 
-	_, _, err = n.client.Jobs().Deregister(jobID, false, &nomadApi.WriteOptions{Namespace: jobID})
+	_, _, err = n.client.Jobs().Deregister(jobID, false, &nomadApi.WriteOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
@@ -113,23 +185,28 @@ func (n *NomadClient) StopJob(ctx context.Context, jobID string) error {
 	return nil
 }
 
-func (n *NomadClient) RestartJob(ctx context.Context, jobID string) error {
-	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: jobID})
+func (n *NomadClient) RestartJob(ctx context.Context, jobID, namespace string) error {
+	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: namespace})
+	if err != nil {
+		return err
+	}
+
+	allocs, err := n.getAllocations(ctx, jobID, namespace)
 	if err != nil {
 		return err
 	}
 
-	allocs, err := n.getAllocations(ctx, jobID, jobID)
+	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
 
-	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, &nomadApi.QueryOptions{Namespace: jobID})
+	task, err := taskName(alloc)
 	if err != nil {
 		return err
 	}
 
-	err = n.client.Allocations().Restart(alloc, jobID, &nomadApi.QueryOptions{Namespace: jobID})
+	err = n.client.Allocations().Restart(alloc, task, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
@@ -137,13 +214,13 @@ func (n *NomadClient) RestartJob(ctx context.Context, jobID string) error {
 	return nil
 }
 
-func (n *NomadClient) DeleteJob(ctx context.Context, jobID string) error {
-	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: jobID})
+func (n *NomadClient) DeleteJob(ctx context.Context, jobID, namespace string) error {
+	_, _, err := n.client.Jobs().Info(jobID, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
 
-	_, _, err = n.client.Jobs().Deregister(jobID, true, &nomadApi.WriteOptions{Namespace: jobID})
+	_, _, err = n.client.Jobs().Deregister(jobID, true, &nomadApi.WriteOptions{Namespace: namespace})
 	if err != nil {
 		return err
 	}
@@ -151,7 +228,10 @@ func (n *NomadClient) DeleteJob(ctx context.Context, jobID string) error {
 	return nil
 }
 
-func (n *NomadClient) RunCommand(ctx context.Context, jobID, namespace string, stdin io.Reader, stdout, stderr io.Writer, cmd string, args ...string) (int, error) {
+// RunCommand executes cmd in the task of the job's newest allocation. Terminal sizes sent on termSizeCh
+// are forwarded to the session when tty is set.
+func (n *NomadClient) RunCommand(ctx context.Context, jobID, namespace string, tty bool, stdin io.Reader, stdout, stderr io.Writer,
+	termSizeCh <-chan TerminalSize, cmd string, args ...string) (int, error) {
 	allocs, err := n.getAllocations(ctx, jobID, namespace)
 	if err != nil {
 		return 0, err
@@ -162,13 +242,16 @@ func (n *NomadClient) RunCommand(ctx context.Context, jobID, namespace string, s
 		return 0, err
 	}
 
-	var termSizeCh chan nomadApi.TerminalSize
+	task, err := taskName(alloc)
+	if err != nil {
+		return 0, err
+	}
 
 	command := []string{cmd}
 	command = append(command, args...)
 
-	exitCode, err := n.client.Allocations().Exec(ctx, alloc, jobID,
-		true, command, stdin, stdout, stderr, termSizeCh, &nomadApi.QueryOptions{Namespace: namespace})
+	exitCode, err := n.client.Allocations().Exec(ctx, alloc, task,
+		tty, command, stdin, stdout, stderr, termSizeCh, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return 0, err
 	}
@@ -176,84 +259,203 @@ func (n *NomadClient) RunCommand(ctx context.Context, jobID, namespace string, s
 	return exitCode, err
 }
 
-func (n *NomadClient) GetAllocationNetwork(ctx context.Context, jobID, namespace string) (string, []int, error) {
-	allocs, err := n.getAllocations(ctx, jobID, namespace)
-	if err != nil {
-		return "", nil, err
+// PublicIPMeta is the node meta key holding the address players connect to, for nodes behind NAT
+const PublicIPMeta = "public_ip"
+
+// AllocationPort is a port of the job's network block and the host port it was mapped to
+type AllocationPort struct {
+	Label  string `json:"label"`
+	Value  int    `json:"port"`
+	To     int    `json:"to"`
+	HostIP string `json:"host_ip"`
+}
+
+// AllocationNetwork is where the newest allocation of a job can be reached
+type AllocationNetwork struct {
+	AllocID      string
+	ClientStatus string
+	NodeID       string
+	// PublicIP is the node's public_ip meta, or the address the ports are bound to when it is not set
+	PublicIP string
+	Ports    []AllocationPort
+}
+
+// Port returns the host port mapped to the port label
+func (a *AllocationNetwork) Port(label string) (int, bool) {
+	for _, port := range a.Ports {
+		if port.Label == label {
+			return port.Value, true
+		}
 	}
+	return 0, false
+}
 
-	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, &nomadApi.QueryOptions{Namespace: namespace})
+// GetAllocationNetwork returns the address and the mapped ports of the job's newest allocation
+func (n *NomadClient) GetAllocationNetwork(ctx context.Context, jobID, namespace string) (*AllocationNetwork, error) {
+	alloc, err := n.LatestAllocation(ctx, jobID, namespace)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	if alloc.Resources == nil || len(alloc.Resources.Networks) == 0 {
-		return "", nil, errors.New("no network resources")
+	network := &AllocationNetwork{AllocID: alloc.ID, ClientStatus: alloc.ClientStatus, NodeID: alloc.NodeID, Ports: allocationPorts(alloc)}
+	if len(network.Ports) == 0 {
+		return nil, errors.New("no network resources")
 	}
 
-	ip := alloc.Resources.Networks[0].IP
-
-	var ports []int
-	for _, port := range alloc.Resources.Networks[0].DynamicPorts {
-		if port.Value == 0 {
-			continue
-		}
-		ports = append(ports, port.Value)
+	network.PublicIP, err = n.nodeIP(ctx, alloc.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if network.PublicIP == "" {
+		network.PublicIP = network.Ports[0].HostIP
+	}
+	if network.PublicIP == "" {
+		return nil, errors.New("no node ip")
 	}
 
-	return ip, ports, nil
+	return network, nil
 }
 
-func (n *NomadClient) GetNodeIP(ctx context.Context, gsID string) (string, error) {
-	allocs, err := n.getAllocations(ctx, gsID, gsID)
-	if err != nil {
-		return "", err
+// allocationPorts collects the mapped ports of a group network, falling back to the deprecated task networks
+func allocationPorts(alloc *nomadApi.Allocation) []AllocationPort {
+	var ports []AllocationPort
+
+	if alloc.AllocatedResources != nil {
+		for _, port := range alloc.AllocatedResources.Shared.Ports {
+			ports = append(ports, AllocationPort{Label: port.Label, Value: port.Value, To: port.To, HostIP: port.HostIP})
+		}
+		if len(ports) > 0 {
+			return ports
+		}
+	}
+
+	if alloc.Resources == nil {
+		return nil
 	}
+	for _, network := range alloc.Resources.Networks {
+		for _, port := range append(network.ReservedPorts, network.DynamicPorts...) {
+			if port.Value == 0 {
+				continue
+			}
+			ports = append(ports, AllocationPort{Label: port.Label, Value: port.Value, To: port.To, HostIP: network.IP})
+		}
+	}
+	return ports
+}
 
-	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, &nomadApi.QueryOptions{Namespace: gsID})
+func (n *NomadClient) GetNodeIP(ctx context.Context, jobID, namespace string) (string, error) {
+	network, err := n.GetAllocationNetwork(ctx, jobID, namespace)
 	if err != nil {
 		return "", err
 	}
+	return network.PublicIP, nil
+}
 
-	if alloc.NodeID == "" {
+// nodeIP returns the node's public_ip meta or the host of its http address, empty when neither is set
+func (n *NomadClient) nodeIP(ctx context.Context, nodeID string) (string, error) {
+	if nodeID == "" {
 		return "", errors.New("no node id")
 	}
 
-	node, _, err := n.client.Nodes().Info(alloc.NodeID, &nomadApi.QueryOptions{Namespace: gsID})
+	node, _, err := n.client.Nodes().Info(nodeID, (&nomadApi.QueryOptions{}).WithContext(ctx))
 	if err != nil {
 		return "", err
 	}
 
-	addr := node.HTTPAddr
-	if addr == "" {
-		return "", errors.New("no node address")
+	if ip := node.Meta[PublicIPMeta]; ip != "" {
+		return ip, nil
 	}
-
-	// obtain IP from addr
-	ip := strings.Split(addr, ":")[0]
-	if ip == "" {
-		return "", errors.New("no node ip")
+	if node.HTTPAddr == "" {
+		return "", nil
+	}
+	host, _, err := net.SplitHostPort(node.HTTPAddr)
+	if err != nil {
+		return node.HTTPAddr, nil
 	}
+	return host, nil
+}
 
-	return ip, nil
+// ResourceUsage is the resource usage of a job's newest allocation, normalized against what it was allocated
+type ResourceUsage struct {
+	AllocID   string    `json:"alloc_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// CPUPercent is the used share of the allocated cpu, it can exceed 100 when the task bursts
+	CPUPercent       float64 `json:"cpu_percent"`
+	CPUMHz           float64 `json:"cpu_mhz"`
+	CPULimitMHz      int64   `json:"cpu_limit_mhz"`
+	MemoryBytes      uint64  `json:"memory_bytes"`
+	MemoryLimitBytes uint64  `json:"memory_limit_bytes"`
+	// NetworkReservedMBits is the bandwidth reserved for the allocation, nomad does not report throughput
+	NetworkReservedMBits int `json:"network_reserved_mbits"`
 }
 
-func (n *NomadClient) GetStats(ctx context.Context, id, namespace string) ([]byte, error) {
-	allocs, err := n.getAllocations(ctx, id, namespace)
+// ErrAllocationNotRunning is returned by GetStats when the job's newest allocation is not running
+var ErrAllocationNotRunning = errors.New("allocation is not running")
+
+// GetStats returns the current resource usage of the job's newest allocation
+func (n *NomadClient) GetStats(ctx context.Context, jobID, namespace string) (*ResourceUsage, error) {
+	alloc, err := n.LatestAllocation(ctx, jobID, namespace)
 	if err != nil {
 		return nil, err
 	}
+	if alloc.ClientStatus != nomadApi.AllocClientStatusRunning {
+		return nil, ErrAllocationNotRunning
+	}
 
-	stats, err := n.client.Allocations().Stats(&nomadApi.Allocation{ID: allocs[0].ID}, &nomadApi.QueryOptions{Namespace: namespace})
+	stats, err := n.client.Allocations().Stats(alloc, (&nomadApi.QueryOptions{Namespace: namespace}).WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(stats)
+	usage := &ResourceUsage{AllocID: alloc.ID, Timestamp: time.Now()}
+	if stats.Timestamp > 0 {
+		usage.Timestamp = time.Unix(0, stats.Timestamp)
+	}
+	if stats.ResourceUsage != nil {
+		if cpu := stats.ResourceUsage.CpuStats; cpu != nil {
+			usage.CPUMHz = cpu.TotalTicks
+		}
+		if memory := stats.ResourceUsage.MemoryStats; memory != nil {
+			// cgroups v2 only reports usage
+			usage.MemoryBytes = memory.RSS
+			if usage.MemoryBytes == 0 {
+				usage.MemoryBytes = memory.Usage
+			}
+		}
+	}
+
+	if resources := alloc.AllocatedResources; resources != nil {
+		for _, task := range resources.Tasks {
+			usage.CPULimitMHz += task.Cpu.CpuShares
+			memoryMB := task.Memory.MemoryMB
+			if task.Memory.MemoryMaxMB > memoryMB {
+				memoryMB = task.Memory.MemoryMaxMB
+			}
+			usage.MemoryLimitBytes += uint64(memoryMB) * 1024 * 1024
+			for _, network := range task.Networks {
+				usage.NetworkReservedMBits += intValue(network.MBits)
+			}
+		}
+		for _, network := range resources.Shared.Networks {
+			usage.NetworkReservedMBits += intValue(network.MBits)
+		}
+	}
+	if usage.CPULimitMHz > 0 {
+		usage.CPUPercent = usage.CPUMHz / float64(usage.CPULimitMHz) * 100
+	}
+
+	return usage, nil
+}
+
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
 }
 
-func (n *NomadClient) GetLogs(ctx context.Context, id, namespace, stdType string, offset int64) ([]byte, error) {
-	allocs, err := n.getAllocations(ctx, id, namespace)
+func (n *NomadClient) GetLogs(ctx context.Context, jobID, namespace, stdType string, offset int64) ([]byte, error) {
+	allocs, err := n.getAllocations(ctx, jobID, namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -273,7 +475,12 @@ func (n *NomadClient) GetLogs(ctx context.Context, id, namespace, stdType string
 		return nil, errors.New("invalid std type")
 	}
 
-	logCh, errCh := n.client.AllocFS().Logs(alloc, false, id, logType, nomadApi.OriginStart, int64(offset), ctx.Done(), &nomadApi.QueryOptions{Namespace: namespace})
+	task, err := taskName(alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	logCh, errCh := n.client.AllocFS().Logs(alloc, false, task, logType, nomadApi.OriginStart, int64(offset), ctx.Done(), &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
 		return nil, err
 	}
@@ -286,42 +493,91 @@ func (n *NomadClient) GetLogs(ctx context.Context, id, namespace, stdType string
 	}
 }
 
-func (n *NomadClient) GetSftpPort(ctx context.Context, jobID, namespace string) (int, error) {
+// LatestAllocation returns the newest allocation of the job
+func (n *NomadClient) LatestAllocation(ctx context.Context, jobID, namespace string) (*nomadApi.Allocation, error) {
 	allocs, err := n.getAllocations(ctx, jobID, namespace)
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
 
-	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, &nomadApi.QueryOptions{Namespace: namespace})
+	alloc, _, err := n.client.Allocations().Info(allocs[0].ID, (&nomadApi.QueryOptions{Namespace: namespace}).WithContext(ctx))
 	if err != nil {
-		return -1, err
+		return nil, err
+	}
+
+	return alloc, nil
+}
+
+// StreamLogs follows the stdout or stderr log of the allocation's task starting at offset
+func (n *NomadClient) StreamLogs(ctx context.Context, alloc *nomadApi.Allocation, stdType string, offset int64) (<-chan *nomadApi.StreamFrame, <-chan error) {
+	var logType string
+	switch stdType {
+	case stdoutLogType:
+		logType = nomadApi.FSLogNameStdout
+	case stderrLogType:
+		logType = nomadApi.FSLogNameStderr
+	default:
+		errCh := make(chan error, 1)
+		errCh <- errors.New("invalid std type")
+		return nil, errCh
 	}
 
-	if alloc.Resources == nil || len(alloc.Resources.Networks) == 0 {
-		return -1, errors.New("no network resources")
+	task, err := taskName(alloc)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		return nil, errCh
 	}
 
-	sftpPort := -1
-	for _, port := range alloc.Resources.Networks[0].DynamicPorts {
-		if port.Label == "port-sftp" {
-			sftpPort = port.Value
-			break
+	q := (&nomadApi.QueryOptions{Namespace: alloc.Namespace}).WithContext(ctx)
+	return n.client.AllocFS().Logs(alloc, true, task, logType, nomadApi.OriginStart, offset, ctx.Done(), q)
+}
+
+// WaitAllocationChange blocks until the newest allocation of the job is no longer allocID
+func (n *NomadClient) WaitAllocationChange(ctx context.Context, jobID, namespace, allocID string) error {
+	var index uint64
+	for {
+		q := (&nomadApi.QueryOptions{Namespace: namespace, WaitIndex: index, WaitTime: 5 * time.Minute}).WithContext(ctx)
+		allocs, meta, err := n.client.Jobs().Allocations(jobID, false, q)
+		if err != nil {
+			return err
 		}
+
+		sortAllocations(allocs)
+		if len(allocs) > 0 && allocs[0].ID != allocID {
+			return nil
+		}
+
+		index = meta.LastIndex
+	}
+}
+
+func (n *NomadClient) GetSftpPort(ctx context.Context, jobID, namespace string) (int, error) {
+	network, err := n.GetAllocationNetwork(ctx, jobID, namespace)
+	if err != nil {
+		return -1, err
 	}
 
-	if sftpPort == -1 {
+	sftpPort, ok := network.Port(PortName("sftp"))
+	if !ok {
 		return -1, errors.New("no sftp port found")
 	}
 
 	return sftpPort, nil
 }
 
+// PortName is the label of a job's network port, labeled ports are called port-<label> and the others by
+// their index
+func PortName(label string) string {
+	return "port-" + label
+}
+
 func (n *NomadClient) Name() string {
 	return "nomad"
 }
 
 func (n *NomadClient) CheckHealth(ctx context.Context) error {
-	_, _, err := n.client.Jobs().List(nil)
+	_, _, err := n.client.Jobs().List((&nomadApi.QueryOptions{}).WithContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -333,6 +589,22 @@ func (n *NomadClient) CheckReadiness(ctx context.Context) error {
 	return n.CheckHealth(ctx)
 }
 
+// taskName returns the first task of the allocation's task group, game jobs run a single task
+func taskName(alloc *nomadApi.Allocation) (string, error) {
+	if alloc.Job != nil {
+		tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+		if tg != nil && len(tg.Tasks) > 0 {
+			return tg.Tasks[0].Name, nil
+		}
+	}
+
+	for name := range alloc.TaskStates {
+		return name, nil
+	}
+
+	return "", errors.New("no task found in allocation")
+}
+
 func (n *NomadClient) getAllocations(ctx context.Context, jobID, namespace string) ([]*nomadApi.AllocationListStub, error) {
 	allocs, _, err := n.client.Jobs().Allocations(jobID, false, &nomadApi.QueryOptions{Namespace: namespace})
 	if err != nil {
@@ -343,53 +615,14 @@ func (n *NomadClient) getAllocations(ctx context.Context, jobID, namespace strin
 		return nil, errors.New("no allocations")
 	}
 
-	sort.Slice(allocs, func(i, j int) bool {
-		return allocs[i].CreateTime > allocs[j].CreateTime
-	})
+	sortAllocations(allocs)
 
 	return allocs, nil
 }
 
-func (n *NomadClient) UpdateJobVariables(ctx context.Context, variables map[string]interface{}, jobID, namespace string, serverName string) error {
-
-	log.Println("jobID is ", jobID)
-	job, _, err := n.client.Jobs().Info(jobID,&nomadApi.QueryOptions{})
-	if err != nil {
-		return err
-	}
-	log.Println(variables)
-	for _, taskGroup := range job.TaskGroups {
-		for _, task := range taskGroup.Tasks {
-			if task.Name == serverName {
-				// Create a new map for environment variables
-				newEnv := make(map[string]string)
-
-				// Copy existing environment variables to the new map
-				for key, value := range task.Env {
-					newEnv[key] = value
-				}
-
-				// Update the environment variable or add a new one
-				for key, value := range variables {
-					log.Println(key,value,"key,val")
-					newEnv[key] = value.(string)
-				}
-
-				// Set the updated environment variables for the task
-				task.Env = newEnv
-
-			}
-		}
-	}
-
-	// Submit the updated job specification
-
-	writeOptions := &nomadApi.WriteOptions{}
-	_, _, err = n.client.Jobs().Register(job, writeOptions)
-	if err != nil {
-		return err
-	}
-
-	return nil
-
+// sortAllocations orders allocations newest first
+func sortAllocations(allocs []*nomadApi.AllocationListStub) {
+	sort.Slice(allocs, func(i, j int) bool {
+		return allocs[i].CreateTime > allocs[j].CreateTime
+	})
 }
@@ -0,0 +1,146 @@
+package nomadapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+// planNomad answers the endpoints PlanJob uses, the namespace gs-1 only exists when registered is set
+type planNomad struct {
+	registered bool
+	planned    bool
+}
+
+func (f *planNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jobID, namespace := "gs-1", "gs-1"
+	switch r.URL.Path {
+	case "/v1/jobs/parse":
+		writeJSON(w, &nomadApi.Job{ID: &jobID, Name: &jobID, Namespace: &namespace})
+	case "/v1/namespace/gs-1":
+		if !f.registered {
+			http.Error(w, "namespace not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, &nomadApi.Namespace{Name: namespace})
+	case "/v1/job/gs-1/plan":
+		f.planned = true
+		writeJSON(w, &nomadApi.JobPlanResponse{Diff: &nomadApi.JobDiff{Type: "Edited", ID: jobID}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPlanJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered bool
+		wantPlan   bool
+	}{
+		{name: "never registered", registered: false, wantPlan: false},
+		{name: "registered", registered: true, wantPlan: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &planNomad{registered: tt.registered}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			nc, err := nomadApi.NewClient(&nomadApi.Config{Address: srv.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &NomadClient{client: nc}
+
+			plan, err := client.PlanJob(context.Background(), `job "gs-1" {}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fake.planned != tt.wantPlan {
+				t.Fatalf("planned = %v, want %v", fake.planned, tt.wantPlan)
+			}
+			if !tt.wantPlan {
+				if plan != nil {
+					t.Fatalf("plan = %+v, want nil for a job that was never registered", plan)
+				}
+				return
+			}
+			if plan == nil || plan.Diff == nil || plan.Diff.Type != "Edited" {
+				t.Fatalf("plan = %+v, want the edited diff", plan)
+			}
+		})
+	}
+}
+
+func TestServerJobNames(t *testing.T) {
+	const serverID = "6f1c2d4e-8a9b-4c3d-9e0f-1a2b3c4d5e6f"
+
+	if got := JobIDForServer(serverID); got != "gs-"+serverID {
+		t.Fatalf("JobIDForServer() = %q, want %q", got, "gs-"+serverID)
+	}
+	if got := NamespaceForServer(serverID); got != "gs-"+serverID {
+		t.Fatalf("NamespaceForServer() = %q, want %q", got, "gs-"+serverID)
+	}
+	// another server never shares the job or namespace
+	if JobIDForServer(serverID) == JobIDForServer("7f1c2d4e-8a9b-4c3d-9e0f-1a2b3c4d5e6f") {
+		t.Fatal("two servers share a job id")
+	}
+}
+
+func TestTaskName(t *testing.T) {
+	group, task := "game", "server"
+
+	tests := []struct {
+		name    string
+		alloc   *nomadApi.Allocation
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "task of the job's group",
+			alloc: &nomadApi.Allocation{
+				TaskGroup:  group,
+				Job:        &nomadApi.Job{TaskGroups: []*nomadApi.TaskGroup{{Name: &group, Tasks: []*nomadApi.Task{{Name: task}}}}},
+				TaskStates: map[string]*nomadApi.TaskState{"other": {}},
+			},
+			want: task,
+		},
+		{
+			name:  "task state without a job",
+			alloc: &nomadApi.Allocation{TaskStates: map[string]*nomadApi.TaskState{task: {}}},
+			want:  task,
+		},
+		{
+			name: "group not in the job",
+			alloc: &nomadApi.Allocation{
+				TaskGroup:  "missing",
+				Job:        &nomadApi.Job{TaskGroups: []*nomadApi.TaskGroup{{Name: &group, Tasks: []*nomadApi.Task{{Name: "other"}}}}},
+				TaskStates: map[string]*nomadApi.TaskState{task: {}},
+			},
+			want: task,
+		},
+		{name: "no task", alloc: &nomadApi.Allocation{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := taskName(tt.alloc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("taskName() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("taskName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"startup-manager/core/config"
+)
+
+// ErrSchemaMismatch is returned by CheckVersion when the database is not at the latest migration
+var ErrSchemaMismatch = errors.New("schema version does not match the migrations")
+
+// MigrationStatus is the schema version of the database and the migrations it is missing
+type MigrationStatus struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+// Migrator applies schema migrations, it holds its own connection so closing it leaves Postgres open
+type Migrator struct {
+	m      *migrate.Migrate
+	source source.Driver
+}
+
+// NewMigrator reads the migrations from cfg.MigrationsPath, or from embedded when no path is configured
+func NewMigrator(cfg *core.DbConfig, embedded fs.FS) (*Migrator, error) {
+	var (
+		src source.Driver
+		err error
+	)
+	if cfg.MigrationsPath != "" {
+		entries, err := os.ReadDir(cfg.MigrationsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat migrations path: %w", err)
+		}
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("no migrations found in %s", cfg.MigrationsPath)
+		}
+		src, err = source.Open(fmt.Sprintf("file://%s", cfg.MigrationsPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open migrations: %w", err)
+		}
+	} else {
+		src, err = iofs.New(embedded, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+		}
+	}
+
+	db, err := sql.Open("postgres", cfg.ConnectionString)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, fmt.Errorf("failed to create postgres driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("migrations", src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("failed to create migrations instance: %w", err)
+	}
+
+	return &Migrator{m: m, source: src}, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down reverts the latest steps migrations
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive")
+	}
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// Goto migrates up or down to the version
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the version without running migrations and clears the dirty flag, it is how a failed migration
+// is marked as resolved after the schema was fixed by hand. -1 means no migration was applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Version returns the applied version and whether its migration failed halfway, 0 when none was applied
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Status returns the applied version, the latest available one and the versions still to apply
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	status := &MigrationStatus{Version: version, Dirty: dirty, Pending: []uint{}}
+
+	next, err := m.source.First()
+	for err == nil {
+		status.Latest = next
+		if next > version {
+			status.Pending = append(status.Pending, next)
+		}
+		next, err = m.source.Next(next)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return status, nil
+}
+
+// CheckVersion fails with ErrSchemaMismatch unless the database is cleanly migrated to the latest migration
+func (m *Migrator) CheckVersion() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w: version %d is dirty", ErrSchemaMismatch, status.Version)
+	}
+	if status.Version != status.Latest {
+		return fmt.Errorf("%w: database is at version %d, expected %d", ErrSchemaMismatch, status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Close closes the migrator's connection and migration source
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	core "startup-manager/core/config"
+)
+
+// DefaultTimeout bounds a single check when the checker was added without its own timeout
+const DefaultTimeout = 2 * time.Second
+
+// Check results
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker is a dependency the service needs, Postgres and NomadClient implement it
+type Checker interface {
+	Name() string
+	CheckHealth(ctx context.Context) error
+	CheckReadiness(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one checker
+type CheckResult struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Report is the body served on the probe paths
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type registration struct {
+	checker Checker
+	timeout time.Duration
+}
+
+// Probe runs the registered checkers and serves their results on the health and ready paths of the config
+type Probe struct {
+	config *core.ProbeConfig
+
+	mu       sync.RWMutex
+	checkers []registration
+}
+
+func NewProbe(config *core.ProbeConfig) *Probe {
+	return &Probe{config: config}
+}
+
+// Add registers a checker, a timeout of 0 uses DefaultTimeout
+func (p *Probe) Add(checker Checker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checkers = append(p.checkers, registration{checker: checker, timeout: timeout})
+}
+
+// HealthPath is where the health report is served
+func (p *Probe) HealthPath() string {
+	return p.config.Prefix + p.config.Health
+}
+
+// ReadyPath is where the readiness report is served
+func (p *Probe) ReadyPath() string {
+	return p.config.Prefix + p.config.Ready
+}
+
+// Health runs CheckHealth of every checker
+func (p *Probe) Health(ctx context.Context) *Report {
+	return p.run(ctx, Checker.CheckHealth)
+}
+
+// Readiness runs CheckReadiness of every checker
+func (p *Probe) Readiness(ctx context.Context) *Report {
+	return p.run(ctx, Checker.CheckReadiness)
+}
+
+// run checks every checker concurrently, each within its own timeout
+func (p *Probe) run(ctx context.Context, check func(Checker, context.Context) error) *Report {
+	p.mu.RLock()
+	checkers := append([]registration(nil), p.checkers...)
+	p.mu.RUnlock()
+
+	report := &Report{Status: StatusOK, Checks: make([]CheckResult, len(checkers))}
+
+	var wg sync.WaitGroup
+	for i, registered := range checkers {
+		wg.Add(1)
+		go func(i int, registered registration) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, registered.timeout)
+			defer cancel()
+
+			started := time.Now()
+			err := runCheck(checkCtx, registered.checker, check)
+			result := CheckResult{
+				Name:     registered.checker.Name(),
+				Status:   StatusOK,
+				Duration: float64(time.Since(started).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}(i, registered)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// runCheck returns when the check finished or its context is done, whichever comes first, so a checker that
+// ignores its context can not hold up the probe
+func runCheck(ctx context.Context, checker Checker, check func(Checker, context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check(checker, ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return errors.New("timed out")
+		}
+		return ctx.Err()
+	}
+}
+
+// ServeHTTP answers the health and ready paths with the report, 503 when a check failed
+func (p *Probe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var report *Report
+	switch r.URL.Path {
+	case p.HealthPath():
+		report = p.Health(r.Context())
+	case p.ReadyPath():
+		report = p.Readiness(r.Context())
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
 	"startup-manager/config"
 	"startup-manager/controller"
+	"startup-manager/core/auth"
 	core "startup-manager/core/config"
 	coreLogger "startup-manager/core/logger"
 	postgres "startup-manager/core/postgres"
@@ -13,29 +17,74 @@ import (
 	"sync"
 
 	nomadapi "startup-manager/core/nomad"
+	"startup-manager/core/probe"
 
 	"go.uber.org/zap"
 )
 
-func main() {
-	var configFile string
+// operationWorkers is the number of workers applying queued operations to nomad
+const operationWorkers = 4
 
-	flag.StringVar(&configFile, "c", "config.yml", "config file")
+func main() {
+	var (
+		configFile     string
+		httpPort       string
+		nomadURL       string
+		dbConnection   string
+		migrationsPath string
+	)
+
+	flag.StringVar(&configFile, "c", "config.yml", "config file, empty to configure from the environment only")
+	flag.StringVar(&httpPort, "http-port", "", "http port, overrides http_port")
+	flag.StringVar(&nomadURL, "nomad-url", "", "nomad address, overrides nomad_url")
+	flag.StringVar(&dbConnection, "db", "", "postgres connection string, overrides db_config.connection_string")
+	flag.StringVar(&migrationsPath, "migrations", "", "migrations directory, overrides db_config.migrations_path and the built in migrations")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: startup-manager [flags] [migrate <command>]\n\nflags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\n%s\n", migrateUsage)
+	}
 	flag.Parse()
 	logger, err := coreLogger.NewDefaultLogger()
 	if err != nil {
 		log.Fatalf("cannot initialize otel logger: %v", err)
 	}
 	logger.Debug("initialized logger")
-	conf, err := core.LoadConfig[*config.Config](configFile)
+	conf, err := core.LoadConfig(configFile, config.EnvPrefix, func(c *config.Config) {
+		// only flags given on the command line override the file and the environment
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "http-port":
+				c.HttpPort = httpPort
+			case "nomad-url":
+				c.NomadURL = nomadURL
+			case "db", "migrations":
+				if c.DbConfig == nil {
+					c.DbConfig = &core.DbConfig{}
+				}
+				if f.Name == "db" {
+					c.DbConfig.ConnectionString = dbConnection
+				} else {
+					c.DbConfig.MigrationsPath = migrationsPath
+				}
+			}
+		})
+	})
 	if err != nil {
-		logger.Error("cannot load config",
+		logger.Fatal("cannot load config",
 			zap.Error(err),
 			zap.String("filename", configFile))
 	}
 	logger.Debug("config loaded", zap.String("filename", configFile))
 	dbConfig := conf.GetDbConfig()
-	logger.Debug("dbconfig is", zap.Any("dbconfig", dbConfig))
+
+	if flag.Arg(0) == "migrate" {
+		err = runMigrateCommand(dbConfig, flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Fatal("migrate failed", zap.Error(err))
+		}
+		return
+	}
 
 	pg, err := postgres.NewPostgres(dbConfig)
 
@@ -46,7 +95,7 @@ func main() {
 	defer pg.Close()
 	logger.Info("postgres initialized")
 
-	startupRepo := database.NewStartupRepository(pg)
+	startupRepo := database.NewStartupRepository(pg, conf.GetAppConfig().AuthorizerTableName)
 	logger.Info("repository initialized")
 
 	// initialize nomad client
@@ -56,13 +105,50 @@ func main() {
 		logger.Error("cannot initialize nomad client", zap.Error(err), zap.String("url", conf.NomadURL))
 		panic(err)
 	}
-	startupUsecase := usecase.NewStartUpUsecase(logger, startupRepo, nomadClient)
+	// without a jwks only api keys authenticate requests
+	var jwtVerifier *auth.JWTVerifier
+	if authConfig := conf.GetAppConfig().Auth; authConfig.JWKSFile != "" {
+		jwtVerifier, err = auth.NewJWTVerifier(auth.JWTConfig{
+			JWKSFile:   authConfig.JWKSFile,
+			Issuer:     authConfig.Issuer,
+			Audience:   authConfig.Audience,
+			UserClaim:  authConfig.UserClaim,
+			RolesClaim: authConfig.RolesClaim,
+		})
+		if err != nil {
+			logger.Fatal("cannot load jwks", zap.Error(err), zap.String("filename", authConfig.JWKSFile))
+		}
+	}
+	startupUsecase := usecase.NewStartUpUsecase(logger, startupRepo, nomadClient, jwtVerifier)
 
 	logger.Info("usecase initialized", zap.Any("usecase", startupUsecase))
 
-	startupController := controller.NewStartupController(logger, startupUsecase)
+	// the probes are served by the http server, which only starts once the schema is migrated, so readiness
+	// is never reported before migrations finished
+	probes := probe.NewProbe(conf.GetAppConfig().Probe)
+	probes.Add(pg, 0)
+	probes.Add(nomadClient, 0)
+
+	appConfig := conf.GetAppConfig()
+	allowedOrigins := append([]string{appConfig.DomainName}, appConfig.CorsDomains...)
+	startupController := controller.NewStartupController(logger, startupUsecase, probes, allowedOrigins)
 	logger.Info("controller initialized")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// refuse to serve a schema this binary was not built for, the http server only starts once it is checked
+	err = prepareSchema(dbConfig)
+	if err != nil {
+		logger.Fatal("cannot use database schema", zap.Error(err), zap.Bool("auto_migrate", *dbConfig.AutoMigrate))
+	}
+	logger.Info("database schema is up to date")
+
+	err = startupUsecase.CheckGameCommands(ctx)
+	if err != nil {
+		logger.Error("cannot check game startup commands", zap.Error(err))
+	}
+
 	var wg sync.WaitGroup
 
 	wg.Add(1)
@@ -70,15 +156,47 @@ func main() {
 	go func() {
 		defer wg.Done()
 
-		logger.Info("starting http server")
+		logger.Info("starting http server", zap.String("port", conf.HttpPort))
 
-		if err := startupController.Start(); err != nil {
+		if err := startupController.Start(":" + conf.HttpPort); err != nil {
 			logger.Error("cannot start http server", zap.Error(err))
 			panic(err)
 		}
 
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		logger.Info("starting nomad event consumer")
+		startupUsecase.RunEventConsumer(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		logger.Info("starting reconciler")
+		startupUsecase.RunReconciler(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		logger.Info("starting stats sampler")
+		startupUsecase.RunStatsSampler(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		logger.Info("starting operation workers", zap.Int("workers", operationWorkers))
+		startupUsecase.RunOperationWorkers(ctx, operationWorkers)
+	}()
+
 	wg.Wait()
 	logger.Info("startup manager server closed")
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	core "startup-manager/core/config"
+	postgres "startup-manager/core/postgres"
+	"startup-manager/migrations"
+)
+
+const migrateUsage = `usage: startup-manager [flags] migrate <command>
+
+commands:
+  up             apply every pending migration
+  down N         revert the latest N migrations
+  goto V         migrate up or down to version V
+  force V        set the version to V without running migrations, after fixing a dirty migration by hand
+  version        print the applied version
+  status         print the applied version and the pending migrations`
+
+// migrateCommand is a parsed migrate subcommand, arg is the N of down or the V of goto and force
+type migrateCommand struct {
+	name string
+	arg  int
+}
+
+// parseMigrateCommand checks the arguments of a migrate subcommand before anything connects to the database
+func parseMigrateCommand(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	command := migrateCommand{name: args[0]}
+	var err error
+	switch command.name {
+	case "up", "version", "status":
+		if len(args) != 1 {
+			return command, fmt.Errorf("%s expects no arguments\n%s", command.name, migrateUsage)
+		}
+	case "down":
+		command.arg, err = intArg(args, "N")
+		if err == nil && command.arg <= 0 {
+			err = fmt.Errorf("steps must be positive")
+		}
+	case "goto":
+		command.arg, err = intArg(args, "V")
+		if err == nil && command.arg < 0 {
+			err = fmt.Errorf("version must not be negative")
+		}
+	case "force":
+		// -1 is a valid version, it marks the database as having no migration applied
+		command.arg, err = intArg(args, "V")
+		if err == nil && command.arg < -1 {
+			err = fmt.Errorf("version must be -1 or more")
+		}
+	default:
+		return command, fmt.Errorf("unknown migrate command %q\n%s", command.name, migrateUsage)
+	}
+	return command, err
+}
+
+// runMigrateCommand runs a migrate subcommand against the configured database and writes its output to out
+func runMigrateCommand(dbConfig *core.DbConfig, args []string, out io.Writer) error {
+	command, err := parseMigrateCommand(args)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := postgres.NewMigrator(dbConfig, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch command.name {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down(command.arg)
+	case "goto":
+		err = migrator.Goto(uint(command.arg))
+	case "force":
+		err = migrator.Force(command.arg)
+	case "status":
+		var status *postgres.MigrationStatus
+		status, err = migrator.Status()
+		if err == nil {
+			printMigrationStatus(out, status)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "version %d%s\n", version, dirtySuffix(dirty))
+	return nil
+}
+
+// prepareSchema applies pending migrations when auto migration is enabled and fails unless the database
+// is at the version of the migrations this binary was built with
+func prepareSchema(dbConfig *core.DbConfig) error {
+	migrator, err := postgres.NewMigrator(dbConfig, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if dbConfig.AutoMigrate != nil && *dbConfig.AutoMigrate {
+		err = migrator.Up()
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+	return migrator.CheckVersion()
+}
+
+func printMigrationStatus(out io.Writer, status *postgres.MigrationStatus) {
+	fmt.Fprintf(out, "version %d%s\n", status.Version, dirtySuffix(status.Dirty))
+	fmt.Fprintf(out, "latest  %d\n", status.Latest)
+	if len(status.Pending) == 0 {
+		fmt.Fprintln(out, "no pending migrations")
+		return
+	}
+
+	pending := make([]string, 0, len(status.Pending))
+	for _, version := range status.Pending {
+		pending = append(pending, strconv.FormatUint(uint64(version), 10))
+	}
+	fmt.Fprintf(out, "pending %s\n", strings.Join(pending, ", "))
+}
+
+func dirtySuffix(dirty bool) string {
+	if dirty {
+		return " (dirty)"
+	}
+	return ""
+}
+
+func intArg(args []string, name string) (int, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("%s %s expects exactly one argument\n%s", args[0], name, migrateUsage)
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number", args[1])
+	}
+	return n, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	postgres "startup-manager/core/postgres"
+)
+
+func TestParseMigrateCommand(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    migrateCommand
+		wantErr string
+	}{
+		{args: []string{"up"}, want: migrateCommand{name: "up"}},
+		{args: []string{"version"}, want: migrateCommand{name: "version"}},
+		{args: []string{"status"}, want: migrateCommand{name: "status"}},
+		{args: []string{"down", "2"}, want: migrateCommand{name: "down", arg: 2}},
+		{args: []string{"goto", "0"}, want: migrateCommand{name: "goto", arg: 0}},
+		{args: []string{"goto", "19"}, want: migrateCommand{name: "goto", arg: 19}},
+		{args: []string{"force", "7"}, want: migrateCommand{name: "force", arg: 7}},
+		{args: []string{"force", "-1"}, want: migrateCommand{name: "force", arg: -1}},
+
+		{args: nil, wantErr: "missing migrate command"},
+		{args: []string{"sideways"}, wantErr: `unknown migrate command "sideways"`},
+		{args: []string{"up", "3"}, wantErr: "up expects no arguments"},
+		{args: []string{"down"}, wantErr: "down N expects exactly one argument"},
+		{args: []string{"down", "1", "2"}, wantErr: "down N expects exactly one argument"},
+		{args: []string{"down", "two"}, wantErr: "two is not a number"},
+		{args: []string{"down", "0"}, wantErr: "steps must be positive"},
+		{args: []string{"down", "-1"}, wantErr: "steps must be positive"},
+		{args: []string{"goto", "-1"}, wantErr: "version must not be negative"},
+		{args: []string{"goto"}, wantErr: "goto V expects exactly one argument"},
+		{args: []string{"force", "-2"}, wantErr: "version must be -1 or more"},
+		{args: []string{"force", "1.5"}, wantErr: "1.5 is not a number"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMigrateCommand(tt.args)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseMigrateCommand(%q) error = %v, want it to contain %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMigrateCommand(%q): unexpected error: %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMigrateCommand(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status postgres.MigrationStatus
+		want   string
+	}{
+		{
+			name:   "up to date",
+			status: postgres.MigrationStatus{Version: 19, Latest: 19},
+			want:   "version 19\nlatest  19\nno pending migrations\n",
+		},
+		{
+			name:   "pending",
+			status: postgres.MigrationStatus{Version: 17, Latest: 19, Pending: []uint{18, 19}},
+			want:   "version 17\nlatest  19\npending 18, 19\n",
+		},
+		{
+			name:   "dirty",
+			status: postgres.MigrationStatus{Version: 18, Dirty: true, Latest: 19, Pending: []uint{19}},
+			want:   "version 18 (dirty)\nlatest  19\npending 19\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printMigrationStatus(&out, &tt.status)
+			if out.String() != tt.want {
+				t.Fatalf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
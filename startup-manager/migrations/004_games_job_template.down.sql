@@ -0,0 +1,6 @@
+begin;
+
+alter table games drop column if exists job_template_version;
+alter table games drop column if exists job_template;
+
+commit;
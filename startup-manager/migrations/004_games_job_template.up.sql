@@ -0,0 +1,47 @@
+begin;
+
+alter table games add column if not exists job_template text not null default '';
+alter table games add column if not exists job_template_version int not null default 1;
+
+UPDATE games SET job_template = $tpl$
+job {{hcl .JobID}} {
+  datacenters = ["dc1"]
+  namespace   = {{hcl .Namespace}}
+  type        = "service"
+
+  group "game" {
+    network {
+{{- range $i, $port := .Ports}}
+      port "port-{{$i}}" {
+        to = {{$port}}
+      }
+{{- end}}
+    }
+
+    task "game" {
+      driver = "docker"
+
+      config {
+        image   = {{hcl .Image}}
+        ports   = [{{range $i, $port := .Ports}}{{if $i}}, {{end}}"port-{{$i}}"{{end}}]
+        volumes = [{{range $i, $volume := .Volumes}}{{if $i}}, {{end}}{{hcl $volume}}{{end}}]
+      }
+
+      env {
+{{- range $key, $value := .Envs}}
+        {{$key}} = {{hcl $value}}
+{{- end}}
+        STARTUP = {{hcl .StartupCommand}}
+      }
+
+      resources {
+        cpu    = {{.CPU}}
+        memory = {{.Memory}}
+      }
+    }
+  }
+}
+$tpl$
+WHERE name = 'CS2 Server';
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+DROP INDEX IF EXISTS gs_info_job_id_namespace_uindex;
+alter table gs_info drop column if exists namespace;
+alter table gs_info drop column if exists job_id;
+
+commit;
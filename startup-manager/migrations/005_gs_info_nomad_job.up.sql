@@ -0,0 +1,11 @@
+begin;
+
+alter table gs_info add column if not exists job_id text not null default '';
+alter table gs_info add column if not exists namespace text not null default '';
+
+UPDATE gs_info SET job_id = 'gs-' || id::text WHERE job_id = '';
+UPDATE gs_info SET namespace = 'gs-' || id::text WHERE namespace = '';
+
+create unique index if not exists gs_info_job_id_namespace_uindex on gs_info (namespace, job_id) where job_id <> '';
+
+commit;
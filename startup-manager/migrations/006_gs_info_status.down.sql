@@ -0,0 +1,6 @@
+begin;
+
+DROP INDEX IF EXISTS startups_info_server_id_index;
+alter table gs_info drop column if exists status;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+alter table gs_info add column if not exists status text not null default 'created';
+
+create index if not exists startups_info_server_id_index on startups_info (server_id) where deleted_at is null;
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+DROP TABLE IF EXISTS console_sessions;
+alter table games drop column if exists console_commands;
+
+commit;
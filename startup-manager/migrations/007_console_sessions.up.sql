@@ -0,0 +1,22 @@
+begin;
+
+alter table games add column if not exists console_commands text[] not null default '{}';
+
+create table if not exists console_sessions (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL PRIMARY KEY,
+    server_id uuid not null,
+    command text not null,
+    remote_addr text not null default '',
+    bytes_in bigint not null default 0,
+    bytes_out bigint not null default 0,
+    exit_code int,
+    error text,
+    started_at timestamp with time zone not null default now(),
+    ended_at timestamp with time zone,
+
+    CONSTRAINT console_sessions_servers_id_fk FOREIGN key(server_id) references gs_info(id) ON DELETE CASCADE
+);
+
+create index if not exists console_sessions_server_id_index on console_sessions (server_id, started_at desc);
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+DROP INDEX IF EXISTS games_name_uindex;
+alter table games drop column if exists deleted_at;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+alter table games add column if not exists deleted_at timestamp;
+
+create unique index if not exists games_name_uindex on games (name) where deleted_at is null;
+
+commit;
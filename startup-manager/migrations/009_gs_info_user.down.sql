@@ -0,0 +1,7 @@
+begin;
+
+DROP INDEX IF EXISTS gs_info_user_id_index;
+DROP INDEX IF EXISTS gs_info_user_id_server_name_uindex;
+alter table gs_info drop column if exists user_id;
+
+commit;
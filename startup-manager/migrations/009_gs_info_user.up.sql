@@ -0,0 +1,9 @@
+begin;
+
+alter table gs_info add column if not exists user_id text not null default '';
+
+-- servers from before owners were tracked have no user and may share names, only owned servers are unique
+create unique index if not exists gs_info_user_id_server_name_uindex on gs_info (user_id, server_name) where deleted_at is null and user_id <> '';
+create index if not exists gs_info_user_id_index on gs_info (user_id) where deleted_at is null;
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+alter table games drop column if exists variables;
+
+commit;
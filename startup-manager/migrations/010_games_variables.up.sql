@@ -0,0 +1,12 @@
+begin;
+
+alter table games add column if not exists variables jsonb not null default '[]';
+
+UPDATE games SET variables = '[
+    {"name": "GAME_TYPE", "type": "enum", "default": "0", "description": "Game type, 0 for classic and 1 for gun game", "options": ["0", "1"], "user_editable": true},
+    {"name": "MAP", "type": "string", "default": "cs2-server", "description": "Map loaded on start", "regex": "[A-Za-z0-9_-]+", "user_editable": true},
+    {"name": "MAX_PLAYERS", "type": "int", "default": "10", "description": "Maximum number of players", "min": 1, "max": 64, "user_editable": true}
+]'::jsonb
+WHERE name = 'CS2 Server';
+
+commit;
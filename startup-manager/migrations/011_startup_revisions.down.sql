@@ -0,0 +1,10 @@
+begin;
+
+alter table gs_info drop column if exists active_startup_id;
+
+drop index if exists startups_info_server_id_revision_uindex;
+alter table startups_info drop column if exists rollback_of;
+alter table startups_info drop column if exists job_spec;
+alter table startups_info drop column if exists revision;
+
+commit;
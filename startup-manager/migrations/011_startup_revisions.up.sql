@@ -0,0 +1,27 @@
+begin;
+
+alter table startups_info add column if not exists revision int;
+alter table startups_info add column if not exists job_spec text not null default '';
+-- a rollback copies the revision it rolls back to into a new one, this is the revision it was copied from
+alter table startups_info add column if not exists rollback_of int;
+
+update startups_info s SET revision = r.revision
+FROM (
+    SELECT id, row_number() over (partition by server_id order by created_at, id) AS revision
+    FROM startups_info
+) r
+WHERE s.id = r.id;
+
+alter table startups_info alter column revision set not null;
+create unique index if not exists startups_info_server_id_revision_uindex on startups_info (server_id, revision);
+
+alter table gs_info add column if not exists active_startup_id uuid
+    CONSTRAINT gs_info_active_startup_fk references startups_info(id) ON DELETE SET NULL;
+
+update gs_info g SET active_startup_id = (
+    SELECT s.id FROM startups_info s
+    WHERE s.server_id = g.id AND s.deleted_at IS NULL
+    ORDER BY s.revision DESC LIMIT 1
+);
+
+commit;
@@ -0,0 +1,9 @@
+begin;
+
+drop table if exists outbox;
+
+alter table startups_info drop column if exists applied_at;
+alter table startups_info drop column if exists apply_error;
+alter table startups_info drop column if exists apply_status;
+
+commit;
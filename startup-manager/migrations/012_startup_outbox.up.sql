@@ -0,0 +1,26 @@
+begin;
+
+alter table startups_info add column if not exists apply_status text not null default 'applied';
+alter table startups_info add column if not exists apply_error text;
+alter table startups_info add column if not exists applied_at TIMESTAMP WITH TIME ZONE;
+
+create table if not exists outbox(
+    id uuid DEFAULT uuid_generate_v4() NOT NULL PRIMARY KEY,
+    kind text not null,
+    server_id uuid not null,
+    startup_id uuid,
+    attempts int not null default 0,
+    last_error text,
+    next_attempt_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT outbox_servers_id_fk FOREIGN key(server_id) references gs_info(id) ON DELETE CASCADE,
+    CONSTRAINT outbox_startups_id_fk FOREIGN key(startup_id) references startups_info(id) ON DELETE CASCADE
+);
+
+create index if not exists outbox_pending_index on outbox (next_attempt_at) where processed_at is null;
+-- entries of a server are claimed one at a time in the order they were queued
+create index if not exists outbox_server_pending_index on outbox (server_id, created_at) where processed_at is null;
+
+commit;
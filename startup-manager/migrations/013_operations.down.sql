@@ -0,0 +1,7 @@
+begin;
+
+alter table outbox drop column if exists operation_id;
+
+drop table if exists operations;
+
+commit;
@@ -0,0 +1,24 @@
+begin;
+
+create table if not exists operations(
+    id uuid DEFAULT uuid_generate_v4() NOT NULL PRIMARY KEY,
+    kind text not null,
+    server_id uuid not null,
+    state text not null default 'queued',
+    messages jsonb not null default '[]',
+    result jsonb,
+    error text,
+    created_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT operations_servers_id_fk FOREIGN key(server_id) references gs_info(id) ON DELETE CASCADE
+);
+
+create index if not exists operations_server_id_index on operations (server_id, created_at desc);
+
+alter table outbox add column if not exists operation_id uuid
+    CONSTRAINT outbox_operations_id_fk references operations(id) ON DELETE SET NULL;
+
+commit;
@@ -0,0 +1,5 @@
+begin;
+
+drop table if exists nomad_event_cursors;
+
+commit;
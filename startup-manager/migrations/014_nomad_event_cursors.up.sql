@@ -0,0 +1,9 @@
+begin;
+
+create table if not exists nomad_event_cursors(
+    consumer text not null PRIMARY KEY,
+    last_index bigint not null,
+    updated_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP
+);
+
+commit;
@@ -0,0 +1,21 @@
+begin;
+
+create table if not exists server_stats(
+    server_id uuid not null,
+    resolution text not null,
+    bucket TIMESTAMP WITH TIME ZONE not null,
+    cpu_percent double precision not null,
+    cpu_percent_max double precision not null,
+    memory_bytes bigint not null,
+    memory_bytes_max bigint not null,
+    memory_limit_bytes bigint not null,
+    -- the bandwidth nomad reserved for the allocation, nomad does not report measured traffic
+    network_reserved_mbits integer not null,
+    samples integer not null DEFAULT 1,
+    PRIMARY KEY (server_id, resolution, bucket),
+    CONSTRAINT server_stats_servers_id_fk FOREIGN key(server_id) references gs_info(id) ON DELETE CASCADE
+);
+
+create index if not exists server_stats_resolution_bucket_idx on server_stats(resolution, bucket);
+
+commit;
@@ -0,0 +1,10 @@
+begin;
+
+UPDATE games SET job_template = replace(job_template, '{{hcl (index $.PortNames $i)}}', '"port-{{$i}}"'),
+    job_template_version = job_template_version + 1
+WHERE job_template LIKE '%{{hcl (index $.PortNames $i)}}%';
+
+alter table games drop column if exists connect_template;
+alter table games drop column if exists port_labels;
+
+commit;
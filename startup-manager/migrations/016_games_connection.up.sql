@@ -0,0 +1,14 @@
+begin;
+
+alter table games add column if not exists port_labels text[] not null default '{}';
+alter table games add column if not exists connect_template text not null default '';
+
+-- labeled ports are named port-<label> in the job's network block
+UPDATE games SET job_template = replace(job_template, '"port-{{$i}}"', '{{hcl (index $.PortNames $i)}}'),
+    job_template_version = job_template_version + 1
+WHERE job_template LIKE '%"port-{{$i}}"%';
+
+UPDATE games SET ports = ARRAY[27015], port_labels = ARRAY['game'], connect_template = 'connect {{ip}}:{{game}}'
+WHERE name = 'CS2 Server' AND ports = '{}';
+
+commit;
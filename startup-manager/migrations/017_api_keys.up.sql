@@ -0,0 +1,18 @@
+begin;
+
+-- the default authorizer table, services configured with another authorizer_table_name need the same columns
+create table if not exists api_keys(
+    id uuid DEFAULT uuid_generate_v4() NOT NULL PRIMARY KEY,
+    user_id text not null,
+    name text not null DEFAULT '',
+    key_hash text not null,
+    roles text[] not null DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+create unique index if not exists api_keys_key_hash_uindex on api_keys (key_hash);
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+drop table if exists user_quotas;
+drop table if exists plans;
+alter table games drop column if exists disk;
+
+commit;
@@ -0,0 +1,36 @@
+begin;
+
+-- ephemeral disk of a game's servers in MB, 300 is nomad's default
+alter table games add column if not exists disk int not null default 300;
+
+-- limits of a plan are totals over all servers of a user, cpu in MHz and memory and disk in MB
+create table if not exists plans(
+    id uuid DEFAULT uuid_generate_v4() NOT NULL PRIMARY KEY,
+    name text not null,
+    max_servers int not null,
+    max_cpu int not null,
+    max_memory int not null,
+    max_disk int not null,
+    created_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+create unique index if not exists plans_name_uindex on plans (name);
+
+-- users without a quota row are on the default plan, a non null limit overrides the plan's
+create table if not exists user_quotas(
+    user_id text not null PRIMARY KEY,
+    plan_id uuid not null references plans (id),
+    max_servers int,
+    max_cpu int,
+    max_memory int,
+    max_disk int,
+    created_at TIMESTAMP WITH TIME ZONE not null DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+insert into plans (name, max_servers, max_cpu, max_memory, max_disk)
+values ('default', 2, 2000, 4096, 10240)
+on conflict (name) do nothing;
+
+commit;
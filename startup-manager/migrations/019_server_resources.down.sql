@@ -0,0 +1,10 @@
+begin;
+
+alter table plans drop column if exists max_server_memory;
+alter table plans drop column if exists max_server_cpu;
+alter table startups_info drop column if exists memory;
+alter table startups_info drop column if exists cpu;
+alter table gs_info drop column if exists memory;
+alter table gs_info drop column if exists cpu;
+
+commit;
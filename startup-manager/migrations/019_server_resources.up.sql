@@ -0,0 +1,15 @@
+begin;
+
+-- per server overrides of the game's cpu in MHz and memory in MB, null keeps the game's
+alter table gs_info add column if not exists cpu int;
+alter table gs_info add column if not exists memory int;
+
+-- the overrides a startup revision was rendered with, activating the revision restores them
+alter table startups_info add column if not exists cpu int;
+alter table startups_info add column if not exists memory int;
+
+-- the most a single server of the plan may be given
+alter table plans add column if not exists max_server_cpu int not null default 2000;
+alter table plans add column if not exists max_server_memory int not null default 4096;
+
+commit;
@@ -0,0 +1,9 @@
+// Package migrations holds the schema migrations built into the binary
+package migrations
+
+import "embed"
+
+// FS holds the NNN_name.up.sql and NNN_name.down.sql files of this directory
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"startup-manager/core/auth"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnauthenticated is returned when a request carries no valid token
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden is returned when the user lacks the role an action requires
+	ErrForbidden = errors.New("permission denied")
+)
+
+// Authenticate resolves a bearer token to its user, tokens shaped like a JWT are verified against the
+// configured JWKS and everything else is looked up as an api key
+func (su *StartUpUsecase) Authenticate(ctx context.Context, token string) (*auth.User, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	if auth.LooksLikeJWT(token) && su.jwtVerifier != nil {
+		user, err := su.jwtVerifier.Verify(token, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return user, nil
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key, err := su.repository.UseAPIKey(ctx, hex.EncodeToString(sum[:]))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &auth.User{ID: key.UserID, Roles: key.Roles, Source: auth.SourceAPIKey}, nil
+}
+
+// currentUser returns the user of the request, a ctx without one is never allowed anything
+func currentUser(ctx context.Context) (*auth.User, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// RequireRole fails with ErrForbidden unless the user of ctx has the role
+func RequireRole(ctx context.Context, role string) error {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	if !user.HasRole(role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// canAccess reports whether the user of ctx owns the server with the owner or is an admin. Servers of other
+// users are reported as not found so their ids can not be probed.
+func canAccess(ctx context.Context, ownerID string) error {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() || user.ID == ownerID {
+		return nil
+	}
+	return ErrServerNotFound
+}
+
+// authorizeServer checks that the user of ctx may access the server, deleted servers included so their
+// operations and startups stay readable by their owner
+func (su *StartUpUsecase) authorizeServer(ctx context.Context, serverID uuid.UUID) error {
+	ownerID, err := su.repository.GetServerUserID(ctx, serverID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrServerNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return canAccess(ctx, ownerID)
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// commandPlaceholderRegex matches anything in double braces so malformed placeholders are reported too
+	commandPlaceholderRegex = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+	// shellSafeRegex matches words the shell passes through unchanged
+	shellSafeRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+)
+
+// PlaceholderError lists the placeholders of a startup command that have no variable or sit inside quotes
+// and the variables the command does not use
+type PlaceholderError struct {
+	Missing []string `json:"missing"`
+	Unused  []string `json:"unused"`
+	Quoted  []string `json:"quoted"`
+}
+
+func (e *PlaceholderError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "unresolved placeholders "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) > 0 {
+		problems = append(problems, "unused variables "+strings.Join(e.Unused, ", "))
+	}
+	if len(e.Quoted) > 0 {
+		problems = append(problems, "placeholders inside quotes "+strings.Join(e.Quoted, ", "))
+	}
+	return "invalid startup command: " + strings.Join(problems, "; ")
+}
+
+// renderCommand replaces every {{NAME}} placeholder of the command with its value in a single pass,
+// so values are never expanded again. Missing and unused variables and placeholders inside quotes are
+// returned as a PlaceholderError.
+func renderCommand(command string, values map[string]string) (string, error) {
+	placeholderErr := &PlaceholderError{Quoted: quotedPlaceholders(command)}
+	used := map[string]bool{}
+	missing := map[string]bool{}
+
+	rendered := commandPlaceholderRegex.ReplaceAllStringFunc(command, func(match string) string {
+		name := commandPlaceholderRegex.FindStringSubmatch(match)[1]
+		value, ok := values[name]
+		if !ok || !envNameRegex.MatchString(name) {
+			missing[name] = true
+			return match
+		}
+		used[name] = true
+		return value
+	})
+
+	for name := range missing {
+		placeholderErr.Missing = append(placeholderErr.Missing, name)
+	}
+	for name := range values {
+		if !used[name] {
+			placeholderErr.Unused = append(placeholderErr.Unused, name)
+		}
+	}
+	if len(placeholderErr.Missing) > 0 || len(placeholderErr.Unused) > 0 || len(placeholderErr.Quoted) > 0 {
+		sort.Strings(placeholderErr.Missing)
+		sort.Strings(placeholderErr.Unused)
+		return "", placeholderErr
+	}
+
+	return rendered, nil
+}
+
+// quotedPlaceholders returns the names of the placeholders that sit inside single or double quotes of the
+// command. Values are quoted as single shell words when rendered, inside double quotes the shell would
+// still expand $(...) and backticks of a value and inside single quotes a value could close the quote.
+func quotedPlaceholders(command string) []string {
+	placeholders := commandPlaceholderRegex.FindAllStringSubmatchIndex(command, -1)
+	quoted := []string{}
+	var quote byte
+
+	for i, next := 0, 0; i < len(command); i++ {
+		if next < len(placeholders) && i == placeholders[next][0] {
+			if quote != 0 {
+				quoted = append(quoted, command[placeholders[next][2]:placeholders[next][3]])
+			}
+			// a placeholder is replaced as a whole, its content does not change the quoting
+			i = placeholders[next][1] - 1
+			next++
+			continue
+		}
+
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			// outside single quotes a backslash escapes the next character, a placeholder is still replaced
+			if next >= len(placeholders) || placeholders[next][0] != i+1 {
+				i++
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+
+	sort.Strings(quoted)
+	return quoted
+}
+
+// shellValues rejects values with control characters and quotes every value as a single shell word,
+// so a rendered startup command can not be broken out of by a variable
+func shellValues(values map[string]string) (map[string]string, error) {
+	v := &ValidationError{}
+	quoted := make(map[string]string, len(values))
+
+	for name, value := range values {
+		if containsControl(value) {
+			v.add("variables."+name, "must not contain control characters")
+			continue
+		}
+		quoted[name] = shellQuote(value)
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return quoted, nil
+}
+
+// shellQuote returns value as a single POSIX shell word, words without special characters are left as they are
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	if shellSafeRegex.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func containsControl(value string) bool {
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
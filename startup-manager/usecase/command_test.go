@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+// injectionPayloads are values a user could send to break out of a rendered startup command
+var injectionPayloads = []string{
+	"; rm -rf /",
+	"$(id)",
+	"`id`",
+	"a'b",
+	`a"b`,
+	"${HOME}",
+	"%{if true}x%{endif}",
+	"&& reboot",
+	"| cat /etc/passwd",
+	"> /tmp/owned",
+	"*",
+	"~root",
+	"",
+	"de_dust2",
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"de_dust2", "de_dust2"},
+		{"", "''"},
+		{"; rm -rf /", "'; rm -rf /'"},
+		{"$(id)", "'$(id)'"},
+		{"`id`", "'`id`'"},
+		{"it's", `'it'\''s'`},
+		{"${HOME}", "'${HOME}'"},
+	}
+
+	for _, tt := range tests {
+		got := shellQuote(tt.value)
+		if got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestShellQuoteRoundTrip has a real shell print each quoted payload, a payload that escaped its quoting
+// would print something else or run a command
+func TestShellQuoteRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell available")
+	}
+
+	for _, payload := range injectionPayloads {
+		out, err := exec.Command(sh, "-c", "printf '%s' "+shellQuote(payload)).Output()
+		if err != nil {
+			t.Errorf("shell failed for %q: %v", payload, err)
+			continue
+		}
+		if string(out) != payload {
+			t.Errorf("shell printed %q for %q", out, payload)
+		}
+	}
+}
+
+func TestShellValuesRejectsControlCharacters(t *testing.T) {
+	for _, value := range []string{"a\nEOF", "a\x00b", "a\rb", "\x1b[2J"} {
+		_, err := shellValues(map[string]string{"MAP": value})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("shellValues(%q) error = %v, want a validation error", value, err)
+		}
+	}
+}
+
+func TestRenderCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		values  map[string]string
+		want    string
+		wantErr *PlaceholderError
+	}{
+		{
+			name:    "plain values",
+			command: "srcds_run +map {{MAP}} +maxplayers {{MAX_PLAYERS}}",
+			values:  map[string]string{"MAP": "de_dust2", "MAX_PLAYERS": "10"},
+			want:    "srcds_run +map de_dust2 +maxplayers 10",
+		},
+		{
+			name:    "values are not expanded again",
+			command: "run {{A}} {{B}}",
+			values:  map[string]string{"A": "{{B}}", "B": "x"},
+			want:    "run {{B}} x",
+		},
+		{
+			name:    "missing and unused",
+			command: "run {{A}}",
+			values:  map[string]string{"B": "x"},
+			wantErr: &PlaceholderError{Missing: []string{"A"}, Unused: []string{"B"}, Quoted: []string{}},
+		},
+		{
+			name:    "placeholder in double quotes",
+			command: `bash -c "echo {{MAP}}"`,
+			values:  map[string]string{"MAP": "x"},
+			wantErr: &PlaceholderError{Quoted: []string{"MAP"}},
+		},
+		{
+			name:    "placeholder wrapped in single quotes",
+			command: `run '{{MAP}}'`,
+			values:  map[string]string{"MAP": "x"},
+			wantErr: &PlaceholderError{Quoted: []string{"MAP"}},
+		},
+		{
+			name:    "escaped quote does not open a string",
+			command: `run \"{{MAP}}`,
+			values:  map[string]string{"MAP": "x"},
+			want:    `run \"x`,
+		},
+		{
+			name:    "placeholder after a closed string",
+			command: `run "a b" {{MAP}} 'c'`,
+			values:  map[string]string{"MAP": "x"},
+			want:    `run "a b" x 'c'`,
+		},
+		{
+			name:    "double quote inside single quotes",
+			command: `run '"' {{MAP}}`,
+			values:  map[string]string{"MAP": "x"},
+			want:    `run '"' x`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderCommand(tt.command, tt.values)
+			if tt.wantErr != nil {
+				var placeholderErr *PlaceholderError
+				if !errors.As(err, &placeholderErr) {
+					t.Fatalf("error = %v, want a placeholder error", err)
+				}
+				if !reflect.DeepEqual(placeholderErr, tt.wantErr) {
+					t.Fatalf("error = %+v, want %+v", placeholderErr, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGenerateStartupCommandPayloads renders every payload into a command and has a real shell print the
+// argument, it has to come out unchanged as a single word
+func TestGenerateStartupCommandPayloads(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell available")
+	}
+
+	for _, payload := range injectionPayloads {
+		command, err := generateStartupCommand("printf '%s|' {{MAP}}", map[string]interface{}{"MAP": payload})
+		if err != nil {
+			t.Errorf("payload %q: %v", payload, err)
+			continue
+		}
+		out, err := exec.Command(sh, "-c", command).Output()
+		if err != nil {
+			t.Errorf("payload %q: shell failed: %v", payload, err)
+			continue
+		}
+		if string(out) != payload+"|" {
+			t.Errorf("payload %q printed %q", payload, out)
+		}
+	}
+}
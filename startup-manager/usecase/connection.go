@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+// ErrServerNotRunning is returned for requests that need a running allocation of the server
+var ErrServerNotRunning = errors.New("server is not running")
+
+// Labels a game can give its ports, a labeled port is exposed to players under its label
+const (
+	PortLabelGame  = "game"
+	PortLabelQuery = "query"
+	PortLabelRcon  = "rcon"
+	PortLabelSftp  = "sftp"
+)
+
+var portLabels = []string{PortLabelGame, PortLabelQuery, PortLabelRcon, PortLabelSftp}
+
+// connectionCacheTTL bounds how long a cached connection is served when an allocation event was missed
+const connectionCacheTTL = 5 * time.Minute
+
+// ConnectionPort is a port of the server as players reach it
+type ConnectionPort struct {
+	Name          string `json:"name"`
+	Label         string `json:"label,omitempty"`
+	Port          int    `json:"port"`
+	ContainerPort int    `json:"container_port"`
+}
+
+// ServerConnection is how players connect to a running server
+type ServerConnection struct {
+	ServerID  uuid.UUID        `json:"server_id"`
+	IP        string           `json:"ip"`
+	Ports     []ConnectionPort `json:"ports"`
+	Connect   string           `json:"connect,omitempty"`
+	AllocID   string           `json:"alloc_id"`
+	FetchedAt time.Time        `json:"fetched_at"`
+}
+
+type connectionEntry struct {
+	connection *ServerConnection
+	expires    time.Time
+}
+
+// connectionCache holds the connection of servers by nomad job, entries are dropped when an event reports
+// a change of the job's allocations
+type connectionCache struct {
+	mu      sync.Mutex
+	entries map[string]connectionEntry
+}
+
+func connectionKey(namespace, jobID string) string {
+	return namespace + "/" + jobID
+}
+
+func (c *connectionCache) get(namespace, jobID string) (*ServerConnection, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[connectionKey(namespace, jobID)]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.connection, true
+}
+
+func (c *connectionCache) put(namespace, jobID string, connection *ServerConnection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]connectionEntry{}
+	}
+	c.entries[connectionKey(namespace, jobID)] = connectionEntry{connection: connection, expires: time.Now().Add(connectionCacheTTL)}
+}
+
+func (c *connectionCache) invalidate(namespace, jobID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, connectionKey(namespace, jobID))
+}
+
+// GetServerConnection returns the public address, the labeled ports and the connect string of a running server
+func (su *StartUpUsecase) GetServerConnection(ctx context.Context, serverID uuid.UUID) (*ServerConnection, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+	if server.Status != models.ServerStatusRunning && server.Status != models.ServerStatusStarting {
+		su.connections.invalidate(server.Namespace, server.JobID)
+		return nil, ErrServerNotRunning
+	}
+
+	if connection, ok := su.connections.get(server.Namespace, server.JobID); ok {
+		return connection, nil
+	}
+
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGameNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := su.nomadClient.GetAllocationNetwork(ctx, server.JobID, server.Namespace)
+	if nomadapi.IsNotFound(err) {
+		return nil, ErrServerNotRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	if network.ClientStatus != nomadApi.AllocClientStatusRunning {
+		return nil, ErrServerNotRunning
+	}
+
+	connection := &ServerConnection{
+		ServerID:  serverID,
+		IP:        network.PublicIP,
+		Ports:     []ConnectionPort{},
+		AllocID:   network.AllocID,
+		FetchedAt: time.Now(),
+	}
+	values := map[string]string{"ip": network.PublicIP}
+	for _, port := range network.Ports {
+		label := portLabel(port.Label)
+		connection.Ports = append(connection.Ports, ConnectionPort{Name: port.Label, Label: label, Port: port.Value, ContainerPort: port.To})
+		if label != "" {
+			values[label] = strconv.Itoa(port.Value)
+		}
+	}
+	connection.Connect = connectString(game.ConnectTemplate, values)
+
+	su.connections.put(server.Namespace, server.JobID, connection)
+	return connection, nil
+}
+
+// portLabel returns the label of a port named port-<label>, empty for ports without a known label
+func portLabel(name string) string {
+	for _, label := range portLabels {
+		if name == nomadapi.PortName(label) {
+			return label
+		}
+	}
+	return ""
+}
+
+// connectString renders the game's connect template with the {{ip}} and {{<label>}} placeholders. Games
+// without a template connect to ip:port of the game port. Templates referring to a port the allocation does
+// not map render empty.
+func connectString(template string, values map[string]string) string {
+	if template == "" {
+		if _, ok := values[PortLabelGame]; !ok {
+			return ""
+		}
+		template = "{{ip}}:{{" + PortLabelGame + "}}"
+	}
+
+	missing := false
+	rendered := commandPlaceholderRegex.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := values[commandPlaceholderRegex.FindStringSubmatch(match)[1]]
+		if !ok {
+			missing = true
+		}
+		return value
+	})
+	if missing {
+		return ""
+	}
+	return rendered
+}
+
+// validatePortLabels checks that port labels line up with the ports and that the connect template only
+// refers to the address and labeled ports
+func validatePortLabels(v *ValidationError, game *models.Game) {
+	if len(game.PortLabels) > 0 && len(game.PortLabels) != len(game.Ports) {
+		v.add("port_labels", "must have one label per port, use an empty label for unlabeled ports")
+	}
+
+	declared := map[string]bool{"ip": true}
+	for _, label := range game.PortLabels {
+		if label == "" {
+			continue
+		}
+		if portLabel(nomadapi.PortName(label)) == "" {
+			v.add("port_labels", "%q must be one of %s", label, strings.Join(portLabels, ", "))
+			continue
+		}
+		if declared[label] {
+			v.add("port_labels", "%s is used twice", label)
+			continue
+		}
+		declared[label] = true
+	}
+
+	for _, match := range commandPlaceholderRegex.FindAllStringSubmatch(game.ConnectTemplate, -1) {
+		if !declared[match[1]] {
+			v.add("connect_template", "placeholder {{%s}} is neither ip nor a port label", match[1])
+		}
+	}
+}
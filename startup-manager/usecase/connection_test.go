@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"reflect"
+	"startup-manager/core/models"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestConnectString(t *testing.T) {
+	values := map[string]string{"ip": "203.0.113.7", PortLabelGame: "27015", PortLabelQuery: "27016"}
+
+	tests := []struct {
+		name     string
+		template string
+		values   map[string]string
+		want     string
+	}{
+		{name: "default template", values: values, want: "203.0.113.7:27015"},
+		{name: "default template without a game port", values: map[string]string{"ip": "203.0.113.7"}, want: ""},
+		{name: "custom template", template: "steam://connect/{{ip}}:{{game}}", values: values, want: "steam://connect/203.0.113.7:27015"},
+		{name: "spaces in placeholders", template: "{{ ip }}:{{ query }}", values: values, want: "203.0.113.7:27016"},
+		{name: "port the allocation does not map", template: "{{ip}}:{{rcon}}", values: values, want: ""},
+		{name: "no placeholders", template: "play.example.com", values: values, want: "play.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := connectString(tt.template, tt.values)
+			if got != tt.want {
+				t.Fatalf("connectString(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"port-game", PortLabelGame},
+		{"port-query", PortLabelQuery},
+		{"port-rcon", PortLabelRcon},
+		{"port-sftp", PortLabelSftp},
+		{"port-0", ""},
+		{"game", ""},
+		{"port-voice", ""},
+	}
+
+	for _, tt := range tests {
+		got := portLabel(tt.name)
+		if got != tt.want {
+			t.Errorf("portLabel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPortNames(t *testing.T) {
+	game := &models.Game{Ports: pq.Int32Array{27015, 27016, 27020}, PortLabels: pq.StringArray{"game", "", "rcon"}}
+	want := []string{"port-game", "port-1", "port-rcon"}
+
+	got := portNames(game)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("portNames() = %v, want %v", got, want)
+	}
+
+	game.PortLabels = nil
+	want = []string{"port-0", "port-1", "port-2"}
+	got = portNames(game)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("portNames() without labels = %v, want %v", got, want)
+	}
+}
+
+func TestValidatePortLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    pq.Int32Array
+		labels   pq.StringArray
+		template string
+		fields   []string
+	}{
+		{name: "no labels", ports: pq.Int32Array{27015}},
+		{name: "labeled", ports: pq.Int32Array{27015, 27016}, labels: pq.StringArray{"game", "query"}, template: "{{ip}}:{{game}}"},
+		{name: "unlabeled port", ports: pq.Int32Array{27015, 27016}, labels: pq.StringArray{"game", ""}},
+
+		{name: "label count", ports: pq.Int32Array{27015, 27016}, labels: pq.StringArray{"game"}, fields: []string{"port_labels"}},
+		{name: "unknown label", ports: pq.Int32Array{27015}, labels: pq.StringArray{"voice"}, fields: []string{"port_labels"}},
+		{name: "label used twice", ports: pq.Int32Array{27015, 27016}, labels: pq.StringArray{"game", "game"}, fields: []string{"port_labels"}},
+		{name: "template with an undeclared port", ports: pq.Int32Array{27015}, labels: pq.StringArray{"game"}, template: "{{ip}}:{{rcon}}", fields: []string{"connect_template"}},
+		{name: "template without labels", ports: pq.Int32Array{27015}, template: "{{ip}}:{{game}}", fields: []string{"connect_template"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &ValidationError{}
+			validatePortLabels(v, &models.Game{Ports: tt.ports, PortLabels: tt.labels, ConnectTemplate: tt.template})
+
+			var got []string
+			for _, field := range v.Fields {
+				got = append(got, field.Field)
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestConnectionCache(t *testing.T) {
+	cache := &connectionCache{}
+	if _, ok := cache.get("gs-1", "gs-1"); ok {
+		t.Fatal("empty cache returned a connection")
+	}
+
+	connection := &ServerConnection{IP: "203.0.113.7"}
+	cache.put("gs-1", "gs-1", connection)
+	got, ok := cache.get("gs-1", "gs-1")
+	if !ok || got != connection {
+		t.Fatalf("get() = %v, %v, want the cached connection", got, ok)
+	}
+	if _, ok := cache.get("gs-2", "gs-1"); ok {
+		t.Fatal("cache returned the connection of another namespace")
+	}
+
+	cache.invalidate("gs-1", "gs-1")
+	if _, ok := cache.get("gs-1", "gs-1"); ok {
+		t.Fatal("invalidated connection is still cached")
+	}
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrCommandNotAllowed is returned when a console command is not in the game's allowlist
+var ErrCommandNotAllowed = errors.New("command is not allowed for this game")
+
+// ConsoleRequest describes an interactive console session requested by a client
+type ConsoleRequest struct {
+	Command    string
+	RemoteAddr string
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	Resize     <-chan nomadapi.TerminalSize
+}
+
+// ResolveConsoleCommand checks the requested command against the game's allowlist,
+// an empty command resolves to the first allowed one
+func (su *StartUpUsecase) ResolveConsoleCommand(ctx context.Context, serverID uuid.UUID, command string) (string, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return "", err
+	}
+
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if err != nil {
+		return "", err
+	}
+
+	return allowedConsoleCommand(game, command)
+}
+
+// RunConsole bridges the request's streams to an exec session in the server's game task until the
+// command exits or ctx is cancelled. Every session is audited in console_sessions.
+func (su *StartUpUsecase) RunConsole(ctx context.Context, serverID uuid.UUID, request ConsoleRequest) (int, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return 0, err
+	}
+
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if err != nil {
+		return 0, err
+	}
+
+	command, err := allowedConsoleCommand(game, request.Command)
+	if err != nil {
+		return 0, err
+	}
+
+	session := &models.ConsoleSession{
+		ServerID:   serverID,
+		Command:    command,
+		RemoteAddr: request.RemoteAddr,
+	}
+	session.ID, err = su.repository.CreateConsoleSession(ctx, session)
+	if err != nil {
+		return 0, err
+	}
+	su.logger.Info("console session opened",
+		zap.String("session_id", session.ID.String()),
+		zap.String("server_id", server.ID),
+		zap.String("command", command),
+		zap.String("remote_addr", request.RemoteAddr))
+
+	var bytesIn, bytesOut atomic.Int64
+	stdin := &countingReader{reader: request.Stdin, count: &bytesIn}
+	stdout := &countingWriter{writer: request.Stdout, count: &bytesOut}
+	stderr := &countingWriter{writer: request.Stderr, count: &bytesOut}
+
+	args := strings.Fields(command)
+	exitCode, execErr := su.nomadClient.RunCommand(ctx, server.JobID, server.Namespace, true,
+		stdin, stdout, stderr, request.Resize, args[0], args[1:]...)
+
+	session.BytesIn = bytesIn.Load()
+	session.BytesOut = bytesOut.Load()
+	if execErr != nil {
+		message := execErr.Error()
+		session.Error = &message
+	} else {
+		session.ExitCode = &exitCode
+	}
+
+	// the client disconnecting cancels ctx, the audit record still has to be closed
+	err = su.repository.FinishConsoleSession(context.Background(), session)
+	if err != nil {
+		su.logger.Error("cannot finish console session", zap.String("session_id", session.ID.String()), zap.Error(err))
+	}
+	su.logger.Info("console session closed",
+		zap.String("session_id", session.ID.String()),
+		zap.String("server_id", server.ID),
+		zap.Int64("bytes_in", session.BytesIn),
+		zap.Int64("bytes_out", session.BytesOut),
+		zap.Error(execErr))
+
+	return exitCode, execErr
+}
+
+func allowedConsoleCommand(game *models.Game, command string) (string, error) {
+	command = strings.Join(strings.Fields(command), " ")
+
+	for _, allowed := range game.ConsoleCommands {
+		allowed = strings.Join(strings.Fields(allowed), " ")
+		if allowed == "" {
+			continue
+		}
+		if command == "" || command == allowed {
+			return allowed, nil
+		}
+	}
+
+	return "", ErrCommandNotAllowed
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	writer io.Writer
+	count  *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// VariableChange is a startup variable that differs between two revisions, From or To is nil when the
+// variable was added or removed
+type VariableChange struct {
+	Name string      `json:"name"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// diffVariables lists the variables that differ between from and to, ordered by name
+func diffVariables(from, to map[string]interface{}) []VariableChange {
+	changes := []VariableChange{}
+	for name, value := range to {
+		previous, ok := from[name]
+		if !ok || !reflect.DeepEqual(previous, value) {
+			changes = append(changes, VariableChange{Name: name, From: previous, To: value})
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok {
+			changes = append(changes, VariableChange{Name: name, From: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// diffLines returns the changed lines between two texts, removed lines are prefixed with "-" and added
+// lines with "+". Lines are matched on their longest common subsequence.
+func diffLines(from, to string) []string {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, fmt.Sprintf("-%s", a[i]))
+			i++
+		default:
+			diff = append(diff, fmt.Sprintf("+%s", b[j]))
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, fmt.Sprintf("-%s", a[i]))
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, fmt.Sprintf("+%s", b[j]))
+	}
+
+	return diff
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"time"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+	"go.uber.org/zap"
+)
+
+const (
+	// eventConsumerName is the key of the event consumer's cursor in nomad_event_cursors
+	eventConsumerName = "server-status"
+	// eventReconnectDelay is how long to wait before subscribing again after the event stream broke
+	eventReconnectDelay = 5 * time.Second
+)
+
+// RunEventConsumer follows nomad's event stream until ctx is cancelled and keeps gs_info.status in sync
+// with the jobs, allocations and deployments of the managed namespaces. The index of every processed batch
+// is stored, so a restarted consumer continues where it stopped.
+func (su *StartUpUsecase) RunEventConsumer(ctx context.Context) {
+	for {
+		err := su.consumeEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		su.logger.Warn("nomad event stream closed, reconnecting", zap.Error(err), zap.Duration("delay", eventReconnectDelay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventReconnectDelay):
+		}
+	}
+}
+
+func (su *StartUpUsecase) consumeEvents(ctx context.Context) error {
+	index, err := su.repository.GetEventCursor(ctx, eventConsumerName)
+	if err != nil {
+		return err
+	}
+	if index > 0 {
+		index++
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := su.nomadClient.StreamEvents(streamCtx, index)
+	if err != nil {
+		return err
+	}
+	su.logger.Info("subscribed to nomad events", zap.Uint64("index", index))
+
+	for events := range stream {
+		if events.Err != nil {
+			return events.Err
+		}
+		if events.IsHeartbeat() {
+			continue
+		}
+
+		for i := range events.Events {
+			su.handleEvent(ctx, &events.Events[i])
+		}
+
+		err = su.repository.SaveEventCursor(ctx, eventConsumerName, events.Index)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (su *StartUpUsecase) handleEvent(ctx context.Context, event *nomadApi.Event) {
+	namespace, jobID, status, err := serverStatusFromEvent(event)
+	if err != nil {
+		su.logger.Warn("cannot decode nomad event", zap.String("topic", string(event.Topic)), zap.String("type", event.Type), zap.Error(err))
+		return
+	}
+	if !nomadapi.IsServerNamespace(namespace) {
+		return
+	}
+	// any change of the job or its allocations can move the server to another address or ports
+	if event.Topic == nomadApi.TopicJob || event.Topic == nomadApi.TopicAllocation {
+		su.connections.invalidate(namespace, jobID)
+	}
+	if status == "" {
+		return
+	}
+
+	changed, err := su.repository.UpdateServerStatusByJob(ctx, namespace, jobID, status)
+	if err != nil {
+		su.logger.Error("cannot update server status", zap.String("job_id", jobID), zap.String("status", status), zap.Error(err))
+		return
+	}
+	if changed {
+		su.logger.Info("server status changed",
+			zap.String("namespace", namespace),
+			zap.String("job_id", jobID),
+			zap.String("status", status),
+			zap.String("event", event.Type),
+			zap.Uint64("index", event.Index))
+	}
+}
+
+// serverStatusFromEvent maps a nomad event to the state of the game server whose job it belongs to, an
+// empty status means the event says nothing about the server's state
+func serverStatusFromEvent(event *nomadApi.Event) (namespace, jobID, status string, err error) {
+	switch event.Topic {
+	case nomadApi.TopicJob:
+		job, err := event.Job()
+		if err != nil || job == nil {
+			return "", "", "", err
+		}
+		// a running job is described by its allocations, the job itself only tells when it was stopped
+		if event.Type == "JobDeregistered" || (job.Stop != nil && *job.Stop) {
+			status = models.ServerStatusStopped
+		}
+		return stringValue(job.Namespace), stringValue(job.ID), status, nil
+
+	case nomadApi.TopicAllocation:
+		alloc, err := event.Allocation()
+		if err != nil || alloc == nil {
+			return "", "", "", err
+		}
+		return alloc.Namespace, alloc.JobID, allocationStatus(alloc), nil
+
+	case nomadApi.TopicDeployment:
+		deployment, err := event.Deployment()
+		if err != nil || deployment == nil {
+			return "", "", "", err
+		}
+		switch deployment.Status {
+		case nomadApi.DeploymentStatusRunning:
+			status = models.ServerStatusStarting
+		case nomadApi.DeploymentStatusSuccessful:
+			status = models.ServerStatusRunning
+		case nomadApi.DeploymentStatusFailed:
+			status = models.ServerStatusCrashed
+		}
+		return deployment.Namespace, deployment.JobID, status, nil
+	}
+
+	return "", "", "", nil
+}
+
+// allocationStatus maps the client status of an allocation that should be running to a server state.
+// Allocations being replaced or stopped are ignored, the job event covers a stopped server.
+func allocationStatus(alloc *nomadApi.Allocation) string {
+	if alloc.DesiredStatus != nomadApi.AllocDesiredStatusRun {
+		return ""
+	}
+
+	restarted := false
+	for _, state := range alloc.TaskStates {
+		if state.Restarts > 0 {
+			restarted = true
+		}
+	}
+
+	switch alloc.ClientStatus {
+	case nomadApi.AllocClientStatusPending:
+		if restarted {
+			return models.ServerStatusCrashed
+		}
+		// the image is pulled and the task prepared before it starts
+		return models.ServerStatusInstalling
+
+	case nomadApi.AllocClientStatusRunning:
+		for _, state := range alloc.TaskStates {
+			if state.State != "running" {
+				if state.Restarts > 0 {
+					return models.ServerStatusCrashed
+				}
+				return models.ServerStatusStarting
+			}
+		}
+		if alloc.DeploymentStatus != nil {
+			if alloc.DeploymentStatus.Healthy == nil {
+				return models.ServerStatusStarting
+			}
+			if !*alloc.DeploymentStatus.Healthy {
+				return models.ServerStatusCrashed
+			}
+		}
+		return models.ServerStatusRunning
+
+	case nomadApi.AllocClientStatusFailed, nomadApi.AllocClientStatusLost:
+		return models.ServerStatusCrashed
+
+	case nomadApi.AllocClientStatusComplete:
+		return models.ServerStatusStopped
+	}
+
+	return ""
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"startup-manager/core/models"
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+func TestServerStatusFromEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   *nomadApi.Event
+		jobID   string
+		status  string
+		ignored bool
+	}{
+		{
+			name:  "job registered",
+			event: &nomadApi.Event{Topic: nomadApi.TopicJob, Type: "JobRegistered", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "gs-1", "Namespace": "gs-1"}}},
+			jobID: "gs-1",
+		},
+		{
+			name:   "job stopped",
+			event:  &nomadApi.Event{Topic: nomadApi.TopicJob, Type: "JobRegistered", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "gs-1", "Namespace": "gs-1", "Stop": true}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusStopped,
+		},
+		{
+			name:   "job deregistered",
+			event:  &nomadApi.Event{Topic: nomadApi.TopicJob, Type: "JobDeregistered", Payload: map[string]interface{}{"Job": map[string]interface{}{"ID": "gs-1", "Namespace": "gs-1"}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusStopped,
+		},
+		{
+			name: "allocation running",
+			event: &nomadApi.Event{Topic: nomadApi.TopicAllocation, Payload: map[string]interface{}{"Allocation": map[string]interface{}{
+				"JobID": "gs-1", "Namespace": "gs-1", "DesiredStatus": "run", "ClientStatus": "running",
+			}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusRunning,
+		},
+		{
+			name:   "deployment running",
+			event:  &nomadApi.Event{Topic: nomadApi.TopicDeployment, Payload: map[string]interface{}{"Deployment": map[string]interface{}{"JobID": "gs-1", "Namespace": "gs-1", "Status": "running"}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusStarting,
+		},
+		{
+			name:   "deployment successful",
+			event:  &nomadApi.Event{Topic: nomadApi.TopicDeployment, Payload: map[string]interface{}{"Deployment": map[string]interface{}{"JobID": "gs-1", "Namespace": "gs-1", "Status": "successful"}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusRunning,
+		},
+		{
+			name:   "deployment failed",
+			event:  &nomadApi.Event{Topic: nomadApi.TopicDeployment, Payload: map[string]interface{}{"Deployment": map[string]interface{}{"JobID": "gs-1", "Namespace": "gs-1", "Status": "failed"}}},
+			jobID:  "gs-1",
+			status: models.ServerStatusCrashed,
+		},
+		{
+			name:  "deployment paused",
+			event: &nomadApi.Event{Topic: nomadApi.TopicDeployment, Payload: map[string]interface{}{"Deployment": map[string]interface{}{"JobID": "gs-1", "Namespace": "gs-1", "Status": "paused"}}},
+			jobID: "gs-1",
+		},
+		{name: "other topic", event: &nomadApi.Event{Topic: nomadApi.TopicNode}, ignored: true},
+		{name: "job event without a job", event: &nomadApi.Event{Topic: nomadApi.TopicJob, Payload: map[string]interface{}{}}, ignored: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, jobID, status, err := serverStatusFromEvent(tt.event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.ignored {
+				if namespace != "" || jobID != "" || status != "" {
+					t.Fatalf("serverStatusFromEvent() = %q, %q, %q, want the event ignored", namespace, jobID, status)
+				}
+				return
+			}
+			if namespace != "gs-1" || jobID != tt.jobID || status != tt.status {
+				t.Fatalf("serverStatusFromEvent() = %q, %q, %q, want %q, %q, %q", namespace, jobID, status, "gs-1", tt.jobID, tt.status)
+			}
+		})
+	}
+}
+
+func TestAllocationStatus(t *testing.T) {
+	healthy, unhealthy := true, false
+	task := func(state string, restarts uint64) map[string]*nomadApi.TaskState {
+		return map[string]*nomadApi.TaskState{"game": {State: state, Restarts: restarts}}
+	}
+
+	tests := []struct {
+		name  string
+		alloc nomadApi.Allocation
+		want  string
+	}{
+		{name: "being stopped", alloc: nomadApi.Allocation{DesiredStatus: nomadApi.AllocDesiredStatusStop, ClientStatus: nomadApi.AllocClientStatusRunning}, want: ""},
+		{name: "pending", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusPending, TaskStates: task("pending", 0)}, want: models.ServerStatusInstalling},
+		{name: "pending after a restart", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusPending, TaskStates: task("pending", 1)}, want: models.ServerStatusCrashed},
+		{name: "running", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("running", 0)}, want: models.ServerStatusRunning},
+		{name: "task not running yet", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("pending", 0)}, want: models.ServerStatusStarting},
+		{name: "task restarting", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("pending", 2)}, want: models.ServerStatusCrashed},
+		{
+			name:  "health unknown",
+			alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("running", 0), DeploymentStatus: &nomadApi.AllocDeploymentStatus{}},
+			want:  models.ServerStatusStarting,
+		},
+		{
+			name:  "healthy",
+			alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("running", 0), DeploymentStatus: &nomadApi.AllocDeploymentStatus{Healthy: &healthy}},
+			want:  models.ServerStatusRunning,
+		},
+		{
+			name:  "unhealthy",
+			alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusRunning, TaskStates: task("running", 0), DeploymentStatus: &nomadApi.AllocDeploymentStatus{Healthy: &unhealthy}},
+			want:  models.ServerStatusCrashed,
+		},
+		{name: "failed", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusFailed}, want: models.ServerStatusCrashed},
+		{name: "lost", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusLost}, want: models.ServerStatusCrashed},
+		{name: "complete", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: nomadApi.AllocClientStatusComplete}, want: models.ServerStatusStopped},
+		{name: "unknown client status", alloc: nomadApi.Allocation{DesiredStatus: "run", ClientStatus: "unknown"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocationStatus(&tt.alloc)
+			if got != tt.want {
+				t.Fatalf("allocationStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"startup-manager/core/models"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrGameNotFound is returned when the game does not exist or is deleted
+	ErrGameNotFound = errors.New("game not found")
+	// ErrGameExists is returned when another game already uses the name
+	ErrGameExists = errors.New("a game with this name already exists")
+	// ErrGameInUse is returned when deleting a game that servers still run
+	ErrGameInUse = errors.New("game is used by existing servers")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// defaultGameDisk is the ephemeral disk in MB of games that do not set one, nomad's own default
+	defaultGameDisk = 300
+)
+
+var (
+	// imageReferenceRegex follows the docker reference grammar: [registry[:port]/]path[:tag][@digest]
+	imageReferenceRegex = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+)
+
+// GamePage is one page of the games catalog
+type GamePage struct {
+	Games  []models.Game `json:"games"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// CreateGame validates the game and adds it to the catalog
+func (su *StartUpUsecase) CreateGame(ctx context.Context, game *models.Game) (*models.Game, error) {
+	err := su.validateGame(ctx, game)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := su.repository.CreateGame(ctx, game)
+	if isUniqueViolation(err) {
+		return nil, ErrGameExists
+	}
+	return created, err
+}
+
+// GetGameByID returns a game of the catalog
+func (su *StartUpUsecase) GetGameByID(ctx context.Context, id string) (*models.Game, error) {
+	game, err := su.repository.GetGameByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGameNotFound
+	}
+	return game, err
+}
+
+// ListGames returns a page of the catalog, optionally filtered by name or description
+func (su *StartUpUsecase) ListGames(ctx context.Context, search string, limit, offset int) (*GamePage, error) {
+	limit, offset = pageBounds(limit, offset)
+
+	games, total, err := su.repository.ListGames(ctx, strings.TrimSpace(search), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GamePage{Games: games, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// UpdateGame validates and replaces the game's definition
+func (su *StartUpUsecase) UpdateGame(ctx context.Context, game *models.Game) (*models.Game, error) {
+	_, err := su.GetGameByID(ctx, game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = su.validateGame(ctx, game)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := su.repository.UpdateGame(ctx, game)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGameNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrGameExists
+	}
+	return updated, err
+}
+
+// DeleteGame removes the game from the catalog, games that still have servers cannot be deleted
+func (su *StartUpUsecase) DeleteGame(ctx context.Context, id string) error {
+	game, err := su.GetGameByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	servers, err := su.repository.CountGameServers(ctx, game.Name)
+	if err != nil {
+		return err
+	}
+	if servers > 0 {
+		return ErrGameInUse
+	}
+
+	return su.repository.DeleteGame(ctx, id)
+}
+
+// validateGame checks the game definition and renders its job template against nomad
+func (su *StartUpUsecase) validateGame(ctx context.Context, game *models.Game) error {
+	normalizeGame(game)
+	v := &ValidationError{}
+
+	// a declared schema is the source of the game's default variables
+	if len(game.Variables) > 0 {
+		validateSchema(v, game.Variables)
+		game.DefaultVariables = schemaDefaults(game.Variables)
+	}
+
+	if game.Name == "" {
+		v.add("name", "is required")
+	}
+	if game.Image == "" {
+		v.add("image", "is required")
+	} else if !imageReferenceRegex.MatchString(game.Image) {
+		v.add("image", "%q is not a valid image reference", game.Image)
+	}
+	if game.CPU <= 0 {
+		v.add("cpu", "must be greater than 0")
+	}
+	if game.Memory <= 0 {
+		v.add("memory", "must be greater than 0")
+	}
+	if game.Disk <= 0 {
+		v.add("disk", "must be greater than 0")
+	}
+	for _, port := range game.Ports {
+		if port <= 0 || port > 65535 {
+			v.add("ports", "%d is not a valid port", port)
+		}
+	}
+	validatePortLabels(v, game)
+	for _, env := range game.Envs {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !envNameRegex.MatchString(parts[0]) {
+			v.add("envs", "%q must have the form NAME=value", env)
+		}
+	}
+
+	for _, name := range quotedPlaceholders(game.DefaultStartupCommand) {
+		v.add("default_startup_command", "placeholder {{%s}} must not be inside quotes, variables are quoted when the command is rendered", name)
+	}
+
+	validDefaults := true
+	for _, variable := range game.DefaultVariables {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) != 2 || !envNameRegex.MatchString(parts[0]) {
+			v.add("default_variables", "%q must have the form NAME=value", variable)
+			validDefaults = false
+		}
+	}
+
+	if validDefaults {
+		_, err := generateDefaultStartupCommand(game.DefaultStartupCommand, game.DefaultVariables)
+		var placeholderErr *PlaceholderError
+		if errors.As(err, &placeholderErr) {
+			for _, name := range placeholderErr.Missing {
+				v.add("default_startup_command", "placeholder {{%s}} has no default variable", name)
+			}
+			for _, name := range placeholderErr.Unused {
+				v.add("default_variables", "%s is not used by default_startup_command", name)
+			}
+		} else if err != nil {
+			v.add("default_variables", "%s", err.Error())
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	err := su.validateJobTemplate(ctx, game)
+	if err != nil {
+		v.add("job_template", "%s", err.Error())
+	}
+
+	return v.err()
+}
+
+// normalizeGame trims the game's text fields and replaces nil arrays, the columns are not nullable
+func normalizeGame(game *models.Game) {
+	game.Name = strings.TrimSpace(game.Name)
+	game.Image = strings.TrimSpace(game.Image)
+	game.DefaultStartupCommand = strings.TrimSpace(game.DefaultStartupCommand)
+	game.ConnectTemplate = strings.TrimSpace(game.ConnectTemplate)
+	if game.Disk == 0 {
+		game.Disk = defaultGameDisk
+	}
+
+	if game.Envs == nil {
+		game.Envs = pq.StringArray{}
+	}
+	if game.Ports == nil {
+		game.Ports = pq.Int32Array{}
+	}
+	if game.PortLabels == nil {
+		game.PortLabels = pq.StringArray{}
+	}
+	if game.Volumes == nil {
+		game.Volumes = pq.StringArray{}
+	}
+	if game.Args == nil {
+		game.Args = pq.StringArray{}
+	}
+	if game.DefaultVariables == nil {
+		game.DefaultVariables = pq.StringArray{}
+	}
+	if game.ConsoleCommands == nil {
+		game.ConsoleCommands = pq.StringArray{}
+	}
+	if game.Variables == nil {
+		game.Variables = models.VariableSchema{}
+	}
+}
+
+// pageBounds applies the default and maximum page size to a list request
+func pageBounds(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// CheckGameCommands renders the default startup command of every game and logs the games whose command is
+// rejected. Commands stored before placeholders inside quotes were refused can not be rendered anymore
+// until an admin fixes them.
+func (su *StartUpUsecase) CheckGameCommands(ctx context.Context) error {
+	for offset := 0; ; offset += maxPageSize {
+		games, total, err := su.repository.ListGames(ctx, "", maxPageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, game := range games {
+			_, err = generateDefaultStartupCommand(game.DefaultStartupCommand, game.DefaultVariables)
+			if err != nil {
+				su.logger.Error("game has an invalid default startup command",
+					zap.String("game", game.Name),
+					zap.String("game_id", game.ID),
+					zap.Error(err))
+			}
+		}
+
+		if offset+len(games) >= total || len(games) == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"startup-manager/core/models"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{0, 0, defaultPageSize, 0},
+		{-5, -1, defaultPageSize, 0},
+		{10, 30, 10, 30},
+		{maxPageSize, 0, maxPageSize, 0},
+		{maxPageSize + 1, 0, maxPageSize, 0},
+	}
+
+	for _, tt := range tests {
+		limit, offset := pageBounds(tt.limit, tt.offset)
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("pageBounds(%d, %d) = %d, %d, want %d, %d", tt.limit, tt.offset, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestImageReferenceRegex(t *testing.T) {
+	tests := []struct {
+		image string
+		valid bool
+	}{
+		{"nginx", true},
+		{"cm2network/cs2:latest", true},
+		{"ghcr.io/org/game-server:1.2.3", true},
+		{"registry.local:5000/games/minecraft", true},
+		{"itzg/minecraft-server@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", true},
+
+		{"", false},
+		{"Uppercase/Image", false},
+		{"image:tag with space", false},
+		{"image;rm -rf /", false},
+		{"-leading/dash", false},
+		{"image:", false},
+		{"image@sha256:abc", false},
+	}
+
+	for _, tt := range tests {
+		if got := imageReferenceRegex.MatchString(tt.image); got != tt.valid {
+			t.Errorf("imageReferenceRegex.MatchString(%q) = %v, want %v", tt.image, got, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeGame(t *testing.T) {
+	game := &models.Game{Name: "  cs2 ", Image: " cm2network/cs2 ", DefaultStartupCommand: " ./srcds \n", ConnectTemplate: " {{ip}} "}
+	normalizeGame(game)
+
+	if game.Name != "cs2" || game.Image != "cm2network/cs2" || game.DefaultStartupCommand != "./srcds" || game.ConnectTemplate != "{{ip}}" {
+		t.Fatalf("text fields were not trimmed: %+v", game)
+	}
+	if game.Disk != defaultGameDisk {
+		t.Fatalf("disk = %d, want %d", game.Disk, defaultGameDisk)
+	}
+	arrays := map[string]interface{}{
+		"envs":              game.Envs,
+		"ports":             game.Ports,
+		"port_labels":       game.PortLabels,
+		"volumes":           game.Volumes,
+		"args":              game.Args,
+		"default_variables": game.DefaultVariables,
+		"console_commands":  game.ConsoleCommands,
+		"variables":         game.Variables,
+	}
+	for name, array := range arrays {
+		if reflect.ValueOf(array).IsNil() {
+			t.Errorf("%s is nil, the column is not nullable", name)
+		}
+	}
+}
+
+// TestValidateGame only covers rejected games, a valid game is rendered against nomad
+func TestValidateGame(t *testing.T) {
+	su := &StartUpUsecase{}
+	valid := func() *models.Game {
+		return &models.Game{
+			Name:                  "cs2",
+			Image:                 "cm2network/cs2:latest",
+			CPU:                   1000,
+			Memory:                2048,
+			Ports:                 pq.Int32Array{27015},
+			Envs:                  pq.StringArray{"STEAMAPPID=730"},
+			DefaultStartupCommand: "./srcds +map {{MAP}}",
+			DefaultVariables:      pq.StringArray{`MAP="de_dust2"`},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(game *models.Game)
+		fields []string
+	}{
+		{name: "missing name", modify: func(g *models.Game) { g.Name = "  " }, fields: []string{"name"}},
+		{name: "missing image", modify: func(g *models.Game) { g.Image = "" }, fields: []string{"image"}},
+		{name: "invalid image", modify: func(g *models.Game) { g.Image = "cs2; id" }, fields: []string{"image"}},
+		{name: "no cpu", modify: func(g *models.Game) { g.CPU = 0 }, fields: []string{"cpu"}},
+		{name: "negative memory", modify: func(g *models.Game) { g.Memory = -1 }, fields: []string{"memory"}},
+		{name: "negative disk", modify: func(g *models.Game) { g.Disk = -1 }, fields: []string{"disk"}},
+		{name: "invalid ports", modify: func(g *models.Game) { g.Ports = pq.Int32Array{0, 65536} }, fields: []string{"ports", "ports"}},
+		{name: "invalid env", modify: func(g *models.Game) { g.Envs = pq.StringArray{"NO_VALUE", "1BAD=x"} }, fields: []string{"envs", "envs"}},
+		{name: "invalid default variable", modify: func(g *models.Game) { g.DefaultVariables = pq.StringArray{"MAP"} }, fields: []string{"default_variables"}},
+		{name: "unused default variable", modify: func(g *models.Game) { g.DefaultVariables = append(g.DefaultVariables, `MODE="1"`) }, fields: []string{"default_variables"}},
+		{name: "placeholder without default", modify: func(g *models.Game) { g.DefaultStartupCommand += " {{MODE}}" }, fields: []string{"default_startup_command"}},
+		{
+			name: "every problem",
+			modify: func(g *models.Game) {
+				g.Name = ""
+				g.CPU = 0
+				g.Memory = 0
+			},
+			fields: []string{"cpu", "memory", "name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := valid()
+			tt.modify(game)
+
+			err := su.validateGame(context.Background(), game)
+			got := fieldNames(t, err)
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v (%v)", got, tt.fields, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// ServerParams holds the values a game's job template is rendered with
+type ServerParams struct {
+	JobID          string
+	Namespace      string
+	Image          string
+	Ports          []int32
+	PortNames      []string
+	Volumes        []string
+	CPU            int
+	Memory         int
+	Disk           int
+	Command        string
+	Args           []string
+	Envs           map[string]string
+	StartupCommand string
+	Variables      map[string]interface{}
+}
+
+// defaultJobTemplate is used for games that do not carry their own job template
+const defaultJobTemplate = `
+job {{hcl .JobID}} {
+  datacenters = ["dc1"]
+  namespace   = {{hcl .Namespace}}
+  type        = "service"
+
+  group "game" {
+    ephemeral_disk {
+      size = {{.Disk}}
+    }
+
+    network {
+{{- range $i, $port := .Ports}}
+      port {{hcl (index $.PortNames $i)}} {
+        to = {{$port}}
+      }
+{{- end}}
+    }
+
+    task "game" {
+      driver = "docker"
+
+      config {
+        image = {{hcl .Image}}
+{{- if .Ports}}
+        ports = [{{range $i, $port := .Ports}}{{if $i}}, {{end}}{{hcl (index $.PortNames $i)}}{{end}}]
+{{- end}}
+{{- if .Command}}
+        command = {{hcl .Command}}
+{{- end}}
+{{- if .Args}}
+        args = [{{range $i, $arg := .Args}}{{if $i}}, {{end}}{{hcl $arg}}{{end}}]
+{{- end}}
+{{- if .Volumes}}
+        volumes = [{{range $i, $volume := .Volumes}}{{if $i}}, {{end}}{{hcl $volume}}{{end}}]
+{{- end}}
+      }
+
+      env {
+{{- range $key, $value := .Envs}}
+        {{$key}} = {{hcl $value}}
+{{- end}}
+        STARTUP = {{hcl .StartupCommand}}
+      }
+
+      resources {
+        cpu    = {{.CPU}}
+        memory = {{.Memory}}
+      }
+    }
+  }
+}
+`
+
+var (
+	envNameRegex    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	jobIDCleanRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+	jobTemplateFuncs = template.FuncMap{
+		"hcl": hclString,
+	}
+)
+
+// GenerateJobFile renders the game's job template for the given startup command
+func GenerateJobFile(game *models.Game, jobID, namespace, command string, variables map[string]interface{}) (string, error) {
+	params, err := newServerParams(game, jobID, namespace, command, variables)
+	if err != nil {
+		return "", err
+	}
+
+	jobTemplate := game.JobTemplate
+	if strings.TrimSpace(jobTemplate) == "" {
+		jobTemplate = defaultJobTemplate
+	}
+
+	// Prepare template with job template
+	tmpl, err := template.New(game.Name).Funcs(jobTemplateFuncs).Option("missingkey=error").Parse(jobTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid job template for game %s: %w", game.Name, err)
+	}
+
+	// Create buffer to store filled template
+	var filledTemplate strings.Builder
+
+	// Execute template with job params
+	err = tmpl.Execute(&filledTemplate, params)
+	if err != nil {
+		return "", fmt.Errorf("cannot render job template for game %s: %w", game.Name, err)
+	}
+
+	return filledTemplate.String(), nil
+}
+
+func newServerParams(game *models.Game, jobID, namespace, command string, variables map[string]interface{}) (ServerParams, error) {
+	envs, err := parseVariables(game.Envs)
+	if err != nil {
+		return ServerParams{}, err
+	}
+	for key := range envs {
+		if !envNameRegex.MatchString(key) {
+			return ServerParams{}, fmt.Errorf("invalid env name %q", key)
+		}
+	}
+
+	// volumes without a host side are mounted from the task's local directory
+	volumes := make([]string, 0, len(game.Volumes))
+	for i, volume := range game.Volumes {
+		if !strings.Contains(volume, ":") {
+			volume = fmt.Sprintf("local/volume-%d:%s", i, volume)
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return ServerParams{
+		JobID:          jobID,
+		Namespace:      namespace,
+		Image:          game.Image,
+		Ports:          game.Ports,
+		PortNames:      portNames(game),
+		Volumes:        volumes,
+		CPU:            game.CPU,
+		Memory:         game.Memory,
+		Disk:           game.Disk,
+		Command:        game.Command,
+		Args:           game.Args,
+		Envs:           envs,
+		StartupCommand: command,
+		Variables:      variables,
+	}, nil
+}
+
+// portNames names each port of the game after its label, unlabeled ports by their index
+func portNames(game *models.Game) []string {
+	names := make([]string, len(game.Ports))
+	for i := range game.Ports {
+		label := strconv.Itoa(i)
+		if i < len(game.PortLabels) && game.PortLabels[i] != "" {
+			label = game.PortLabels[i]
+		}
+		names[i] = nomadapi.PortName(label)
+	}
+	return names
+}
+
+// parseVariables converts KEY="value" entries into a map, stripping surrounding quotes
+func parseVariables(variables []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(variables))
+	for _, v := range variables {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid variable format: %s", v)
+		}
+		parsed[parts[0]] = strings.Trim(parts[1], "\"")
+	}
+
+	return parsed, nil
+}
+
+// jobIDForGame builds a nomad compatible job id from a game name
+func jobIDForGame(name string) string {
+	return strings.Trim(jobIDCleanRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// hclString quotes a value as an HCL string literal, escaping interpolation sequences
+func hclString(value interface{}) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	s := fmt.Sprintf("%v", value)
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$', '%':
+			// ${ and %{ start template sequences in HCL, doubling escapes them
+			if i+1 < len(s) && s[i+1] == '{' {
+				b.WriteRune(r)
+			}
+			b.WriteRune(r)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"startup-manager/core/models"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHclString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"de_dust2", `"de_dust2"`},
+		{27015, `"27015"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\games`, `"C:\\games"`},
+		{"${HOME}", `"$${HOME}"`},
+		{"%{if true}x%{endif}", `"%%{if true}x%%{endif}"`},
+		{"$HOME 100%", `"$HOME 100%"`},
+		{"a\nEOF\nb", `"a\nEOF\nb"`},
+		{"a\x00b", `"a\u0000b"`},
+		{"a\tb\r", `"a\tb\r"`},
+		{"; rm -rf /", `"; rm -rf /"`},
+		{"$(id) `id`", `"$(id) ` + "`id`" + `"`},
+	}
+
+	for _, tt := range tests {
+		got := hclString(tt.value)
+		if got != tt.want {
+			t.Errorf("hclString(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestHclStringRoundTrip decodes every quoted payload the way HCL reads a quoted template and expects the
+// payload back, so no payload can end the string or start an interpolation
+func TestHclStringRoundTrip(t *testing.T) {
+	payloads := append([]string{"\nEOF\n", "a\x00b", "\"\n}\njob \"x\" {", "$${x}", "%%{x}", "\\"}, injectionPayloads...)
+
+	for _, payload := range payloads {
+		quoted := hclString(payload)
+		decoded, err := decodeHclString(quoted)
+		if err != nil {
+			t.Errorf("hclString(%q) = %s: %v", payload, quoted, err)
+			continue
+		}
+		if decoded != payload {
+			t.Errorf("hclString(%q) = %s decodes to %q", payload, quoted, decoded)
+		}
+	}
+}
+
+func TestGenerateJobFileEscapesValues(t *testing.T) {
+	game := &models.Game{
+		Name:   "test",
+		Image:  "nginx:latest",
+		CPU:    500,
+		Memory: 512,
+		Disk:   300,
+		Ports:  []int32{27015},
+		Envs:   []string{`GREETING=${attr.kernel.name}`},
+	}
+	command := "run $(id) `id` ${node.unique.name} %{if true}x%{endif}\nEOF"
+
+	job, err := GenerateJobFile(game, "gs-1", "gs-1", command, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(job, "\n") {
+		if strings.TrimSpace(line) == "EOF" {
+			t.Fatalf("job has a bare EOF line:\n%s", job)
+		}
+	}
+	for _, sequence := range []string{"${", "%{"} {
+		for i := strings.Index(job, sequence); i >= 0; i = indexFrom(job, sequence, i+1) {
+			if i == 0 || job[i-1] != sequence[0] {
+				t.Fatalf("job has an unescaped %s at %d:\n%s", sequence, i, job)
+			}
+		}
+	}
+	if !strings.Contains(job, `STARTUP = `+hclString(command)) {
+		t.Fatalf("startup command is not quoted:\n%s", job)
+	}
+}
+
+func indexFrom(s, substr string, from int) int {
+	i := strings.Index(s[from:], substr)
+	if i < 0 {
+		return -1
+	}
+	return from + i
+}
+
+// decodeHclString reads a quoted HCL string, failing on anything that would end it early or interpolate
+func decodeHclString(quoted string) (string, error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", strconv.ErrSyntax
+	}
+	s := quoted[1 : len(quoted)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\n' || c == '\r' || c == 0:
+			return "", strconv.ErrSyntax
+		case c == '\\':
+			if i+1 >= len(s) {
+				return "", strconv.ErrSyntax
+			}
+			i++
+			switch s[i] {
+			case '\\', '"':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(s) {
+					return "", strconv.ErrSyntax
+				}
+				r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", err
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", strconv.ErrSyntax
+			}
+		case (c == '$' || c == '%') && i+1 < len(s) && s[i+1] == '{':
+			// a single $ or % before { starts an interpolation or directive
+			return "", strconv.ErrSyntax
+		case (c == '$' || c == '%') && i+2 < len(s) && s[i+1] == c && s[i+2] == '{':
+			b.WriteByte(c)
+			b.WriteByte('{')
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"time"
+
+	"github.com/google/uuid"
+	nomadApi "github.com/hashicorp/nomad/api"
+	"go.uber.org/zap"
+)
+
+const (
+	// LogEventOutput carries console output of one of the log streams
+	LogEventOutput = "log"
+	// LogEventAllocation announces that the stream moved to a new allocation
+	LogEventAllocation = "allocation"
+
+	// logReconnectDelay is how long to wait before following an interrupted log stream again
+	logReconnectDelay = 2 * time.Second
+
+	// file events nomad sends when a followed log file is truncated or deleted, the stream starts over
+	logFileTruncated = "file truncated"
+	logFileDeleted   = "file deleted"
+)
+
+// LogCursor is the position a console log stream resumes from
+type LogCursor struct {
+	AllocID string
+	Stdout  int64
+	Stderr  int64
+}
+
+// LogEvent is sent to the client for every log frame and allocation switch
+type LogEvent struct {
+	Type   string
+	Stream string
+	Data   []byte
+	Cursor LogCursor
+}
+
+type logResult struct {
+	replaced bool
+	err      error
+}
+
+type logFrame struct {
+	stream string
+	frame  *nomadApi.StreamFrame
+}
+
+// logOffset returns the offset a stream resumes from after the frame. A frame's data starts at its offset, a
+// truncated or deleted file starts over at 0.
+func logOffset(offset int64, frame *nomadApi.StreamFrame) int64 {
+	switch frame.FileEvent {
+	case logFileTruncated, logFileDeleted:
+		return 0
+	}
+	if len(frame.Data) == 0 {
+		return offset
+	}
+	return frame.Offset + int64(len(frame.Data))
+}
+
+// StreamServerLogs follows stdout and stderr of the server's newest allocation and calls send for every event
+// until ctx is done or send fails. It resumes from cursor and moves on when the allocation is replaced.
+func (su *StartUpUsecase) StreamServerLogs(ctx context.Context, serverID uuid.UUID, cursor LogCursor, send func(LogEvent) error) error {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		alloc, err := su.nomadClient.LatestAllocation(ctx, server.JobID, server.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if alloc.ID != cursor.AllocID {
+			cursor = LogCursor{AllocID: alloc.ID}
+			err = send(LogEvent{Type: LogEventAllocation, Cursor: cursor})
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor, err = su.followAllocationLogs(ctx, server, alloc, cursor, send)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// followAllocationLogs streams the logs of one allocation until it is replaced or a stream is interrupted
+func (su *StartUpUsecase) followAllocationLogs(ctx context.Context, server *models.GameServerInfo, alloc *nomadApi.Allocation,
+	cursor LogCursor, send func(LogEvent) error) (LogCursor, error) {
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frames := make(chan logFrame)
+	results := make(chan logResult, 3)
+
+	follow := func(stream string, offset int64) {
+		streamFrames, errs := su.nomadClient.StreamLogs(streamCtx, alloc, stream, offset)
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case err := <-errs:
+				results <- logResult{err: err}
+				return
+			case frame, ok := <-streamFrames:
+				if !ok {
+					results <- logResult{}
+					return
+				}
+				select {
+				case frames <- logFrame{stream: stream, frame: frame}:
+				case <-streamCtx.Done():
+					return
+				}
+			}
+		}
+	}
+	go follow(nomadapi.LogTypeStdout, cursor.Stdout)
+	go follow(nomadapi.LogTypeStderr, cursor.Stderr)
+
+	go func() {
+		err := su.nomadClient.WaitAllocationChange(streamCtx, server.JobID, server.Namespace, alloc.ID)
+		results <- logResult{replaced: err == nil, err: err}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return cursor, nil
+		case f := <-frames:
+			if f.stream == nomadapi.LogTypeStdout {
+				cursor.Stdout = logOffset(cursor.Stdout, f.frame)
+			} else {
+				cursor.Stderr = logOffset(cursor.Stderr, f.frame)
+			}
+			if len(f.frame.Data) == 0 {
+				continue
+			}
+			err := send(LogEvent{Type: LogEventOutput, Stream: f.stream, Data: f.frame.Data, Cursor: cursor})
+			if err != nil {
+				return cursor, err
+			}
+		case result := <-results:
+			if result.replaced {
+				return cursor, nil
+			}
+			if result.err != nil && ctx.Err() == nil {
+				su.logger.Warn("log stream interrupted",
+					zap.String("server_id", server.ID),
+					zap.String("alloc_id", alloc.ID),
+					zap.Error(result.err))
+			}
+
+			// give nomad a moment before following the allocation again from the current offsets
+			select {
+			case <-ctx.Done():
+			case <-time.After(logReconnectDelay):
+			}
+			return cursor, nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+func TestLogOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+		frame  nomadApi.StreamFrame
+		want   int64
+	}{
+		{name: "first frame", frame: nomadApi.StreamFrame{Offset: 0, Data: []byte("hello")}, want: 5},
+		{name: "frame after the cursor", offset: 5, frame: nomadApi.StreamFrame{Offset: 5, Data: []byte("world")}, want: 10},
+		{name: "resumed mid file", offset: 3, frame: nomadApi.StreamFrame{Offset: 100, Data: []byte("abc")}, want: 103},
+		{name: "heartbeat", offset: 42, frame: nomadApi.StreamFrame{}, want: 42},
+		{name: "truncated", offset: 42, frame: nomadApi.StreamFrame{Offset: 42, FileEvent: logFileTruncated}, want: 0},
+		{name: "deleted", offset: 42, frame: nomadApi.StreamFrame{FileEvent: logFileDeleted}, want: 0},
+		{name: "data after truncation", offset: 0, frame: nomadApi.StreamFrame{Offset: 0, Data: []byte("new")}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logOffset(tt.offset, &tt.frame)
+			if got != tt.want {
+				t.Fatalf("logOffset(%d) = %d, want %d", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"startup-manager/core/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrOperationNotFound is returned when the operation does not exist
+var ErrOperationNotFound = errors.New("operation not found")
+
+const operationWatchInterval = time.Second
+
+// GetOperation returns the operation with its progress messages and result
+func (su *StartUpUsecase) GetOperation(ctx context.Context, id uuid.UUID) (*models.Operation, error) {
+	operation, err := su.repository.GetOperation(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = su.authorizeServer(ctx, operation.ServerID)
+	if errors.Is(err, ErrServerNotFound) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// WatchOperation calls send with the operation every time it changes until it reaches a final state or
+// ctx is cancelled
+func (su *StartUpUsecase) WatchOperation(ctx context.Context, id uuid.UUID, send func(*models.Operation) error) error {
+	ticker := time.NewTicker(operationWatchInterval)
+	defer ticker.Stop()
+
+	var lastUpdate time.Time
+	for {
+		operation, err := su.GetOperation(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !operation.UpdatedAt.Equal(lastUpdate) {
+			lastUpdate = operation.UpdatedAt
+			err = send(operation)
+			if err != nil {
+				return err
+			}
+		}
+		if operation.Done() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
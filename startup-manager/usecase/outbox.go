@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"startup-manager/usecase/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	// outboxLease is how long a claimed entry stays hidden from other workers
+	outboxLease       = 10 * time.Minute
+	outboxMaxAttempts = 8
+	outboxMaxBackoff  = 5 * time.Minute
+	// deployTimeout bounds how long applying a startup waits for the job to run, it has to stay below the lease
+	deployTimeout = 5 * time.Minute
+)
+
+// errOperationCancelled is returned by an outbox handler when its work no longer applies, for example
+// because a newer startup revision became active or the server was deleted
+var errOperationCancelled = errors.New("operation no longer applies")
+
+// errDeploymentFailed is returned when a registered job did not come up, registering it again would not help
+var errDeploymentFailed = errors.New("deployment failed")
+
+// RunOperationWorkers processes queued operations with the given number of workers until ctx is
+// cancelled. Work is claimed from the outbox, so operations that were in flight when the service stopped
+// are picked up again once their lease runs out. Failed entries are retried with exponential backoff.
+func (su *StartUpUsecase) RunOperationWorkers(ctx context.Context, workers int) {
+	done := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			su.runOperationWorker(ctx)
+		}()
+	}
+	for i := 0; i < workers; i++ {
+		<-done
+	}
+}
+
+func (su *StartUpUsecase) runOperationWorker(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := su.dispatchOutbox(ctx)
+			if err != nil && ctx.Err() == nil {
+				su.logger.Error("cannot dispatch outbox", zap.Error(err))
+			}
+			// after an entry more may be due
+			if err != nil || !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-su.outboxWake:
+		}
+	}
+}
+
+// wakeOutboxDispatcher lets a worker pick up a new entry without waiting for the next poll
+func (su *StartUpUsecase) wakeOutboxDispatcher() {
+	select {
+	case su.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchOutbox claims and processes a single entry, so its lease only has to cover one deployment
+func (su *StartUpUsecase) dispatchOutbox(ctx context.Context) (bool, error) {
+	entry, err := su.repository.ClaimOutboxEntry(ctx, outboxLease)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if entry.OperationID != nil {
+		err = su.repository.StartOperation(ctx, *entry.OperationID, fmt.Sprintf("attempt %d started", entry.Attempts))
+		if err != nil {
+			su.logger.Warn("cannot update operation", zap.String("operation_id", entry.OperationID.String()), zap.Error(err))
+		}
+	}
+	result, applyErr := su.handleOutboxEntry(ctx, entry)
+
+	err = su.finishOutboxEntry(ctx, entry, result, applyErr)
+	if err != nil {
+		su.logger.Error("cannot finish outbox entry", zap.String("entry_id", entry.ID.String()), zap.Error(err))
+	}
+
+	return true, nil
+}
+
+func (su *StartUpUsecase) handleOutboxEntry(ctx context.Context, entry *models.OutboxEntry) (models.OperationResult, error) {
+	server, err := su.repository.GetServer(ctx, entry.ServerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errOperationCancelled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.Kind {
+	case models.OutboxKindApplyStartup:
+		return su.applyStartup(ctx, entry, server)
+	case models.OutboxKindStartServer:
+		return su.startServer(ctx, entry, server)
+	case models.OutboxKindStopServer:
+		return su.stopServer(ctx, entry, server)
+	case models.OutboxKindRestartServer:
+		return su.restartServer(ctx, entry, server)
+	case models.OutboxKindDeleteServer:
+		return su.deleteServer(ctx, entry, server)
+	default:
+		return nil, fmt.Errorf("unknown outbox entry kind %q", entry.Kind)
+	}
+}
+
+// applyStartup registers the job spec of the entry's startup revision if it is still the server's active one
+func (su *StartUpUsecase) applyStartup(ctx context.Context, entry *models.OutboxEntry, server *models.GameServerInfo) (models.OperationResult, error) {
+	if entry.StartupID == nil {
+		return nil, fmt.Errorf("outbox entry %s has no startup", entry.ID)
+	}
+	if server.ActiveStartupID == nil || *server.ActiveStartupID != *entry.StartupID {
+		return nil, errOperationCancelled
+	}
+
+	startup, err := su.repository.GetStartup(ctx, *entry.StartupID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = su.reserveServerResources(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	su.operationProgress(ctx, entry, fmt.Sprintf("registering job %s", server.JobID))
+	registered, err := su.nomadClient.RegisterJob(ctx, startup.JobSpec, nomadapi.RegisterOptions{
+		Wait:       nomadapi.DeployRunning,
+		Timeout:    deployTimeout,
+		AutoRevert: true,
+		Progress: func(message string) {
+			su.operationProgress(ctx, entry, message)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.OperationResult{
+		"startup_id":  startup.ID,
+		"revision":    startup.Revision,
+		"job_version": registered.JobVersion,
+		"deployment":  registered,
+	}
+
+	if registered.Status != nomadapi.DeployRunning {
+		if registered.RevertedTo != nil {
+			err = su.repository.RestorePreviousStartup(ctx, entry.ServerID, startup.ID)
+			if err != nil {
+				return result, err
+			}
+		}
+		return result, fmt.Errorf("%w, job %s: %s", errDeploymentFailed, registered.Status, registered.Reason)
+	}
+
+	err = su.repository.UpdateServerStatus(ctx, entry.ServerID, models.ServerStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+
+	result["status"] = models.ServerStatusRunning
+	return result, nil
+}
+
+// operationProgress adds a progress message to the entry's operation, failures are only logged
+func (su *StartUpUsecase) operationProgress(ctx context.Context, entry *models.OutboxEntry, message string) {
+	if entry.OperationID == nil {
+		return
+	}
+
+	err := su.repository.AddOperationMessage(ctx, *entry.OperationID, message)
+	if err != nil {
+		su.logger.Warn("cannot update operation", zap.String("operation_id", entry.OperationID.String()), zap.Error(err))
+	}
+}
+
+func (su *StartUpUsecase) finishOutboxEntry(ctx context.Context, entry *models.OutboxEntry, result models.OperationResult, applyErr error) error {
+	fields := []zap.Field{
+		zap.String("entry_id", entry.ID.String()),
+		zap.String("kind", entry.Kind),
+		zap.String("server_id", entry.ServerID.String()),
+		zap.Int("attempts", entry.Attempts),
+	}
+
+	switch {
+	case applyErr == nil:
+		su.logger.Info("outbox entry applied", fields...)
+		return su.repository.FinishOutboxEntry(ctx, entry, repository.OutboxOutcome{
+			OperationState: models.OperationStateSucceeded,
+			Result:         result,
+			Message:        "done",
+			StartupStatus:  models.StartupApplyApplied,
+		})
+
+	case errors.Is(applyErr, errOperationCancelled):
+		su.logger.Info("outbox entry cancelled", fields...)
+		return su.repository.FinishOutboxEntry(ctx, entry, repository.OutboxOutcome{
+			OperationState: models.OperationStateCancelled,
+			Message:        "cancelled, a newer change replaced it or the server was deleted",
+			StartupStatus:  models.StartupApplySuperseded,
+		})
+
+	case errors.Is(applyErr, errDeploymentFailed) || errors.As(applyErr, new(*QuotaExceededError)) || nomadapi.IsNotFound(applyErr) ||
+		entry.Attempts >= outboxMaxAttempts:
+		su.logger.Error("outbox entry failed", append(fields, zap.Error(applyErr))...)
+		message := applyErr.Error()
+		return su.repository.FinishOutboxEntry(ctx, entry, repository.OutboxOutcome{
+			OperationState: models.OperationStateFailed,
+			Result:         result,
+			Error:          &message,
+			Message:        "failed: " + message,
+			StartupStatus:  models.StartupApplyFailed,
+		})
+
+	default:
+		delay := outboxBackoff(entry.Attempts)
+		su.logger.Warn("outbox entry will be retried", append(fields, zap.Duration("delay", delay), zap.Error(applyErr))...)
+		return su.repository.RetryOutboxEntry(ctx, entry, delay, applyErr.Error())
+	}
+}
+
+// outboxBackoff doubles the delay with every attempt starting at 5 seconds, capped at outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"startup-manager/core/models"
+	"startup-manager/usecase/repository"
+	"strings"
+)
+
+// ErrPlanNotFound is returned when a quota names a plan that does not exist
+var ErrPlanNotFound = errors.New("plan not found")
+
+// Resources limited by a quota
+const (
+	QuotaResourceServers = "servers"
+	QuotaResourceCPU     = "cpu"
+	QuotaResourceMemory  = "memory"
+	QuotaResourceDisk    = "disk"
+)
+
+// QuotaExceededError is returned when a change would take a user past a limit of their quota
+type QuotaExceededError struct {
+	Resource  string `json:"resource"`
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used"`
+	Requested int    `json:"requested"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s would be %d, the limit is %d", e.Resource, e.Used+e.Requested, e.Limit)
+}
+
+// QuotaReport is a user's quota and how much of it their servers use
+type QuotaReport struct {
+	UserID string             `json:"user_id"`
+	Plan   string             `json:"plan"`
+	Limits models.QuotaLimits `json:"limits"`
+	Usage  *models.QuotaUsage `json:"usage"`
+}
+
+// UserQuotaRequest puts a user on a plan, set limits override the plan's
+type UserQuotaRequest struct {
+	Plan       string `json:"plan"`
+	MaxServers *int   `json:"max_servers"`
+	MaxCPU     *int   `json:"max_cpu"`
+	MaxMemory  *int   `json:"max_memory"`
+	MaxDisk    *int   `json:"max_disk"`
+}
+
+// GetQuota returns the quota and usage of the user of ctx
+func (su *StartUpUsecase) GetQuota(ctx context.Context) (*QuotaReport, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return su.quotaReport(ctx, user.ID)
+}
+
+func (su *StartUpUsecase) quotaReport(ctx context.Context, userID string) (*QuotaReport, error) {
+	quota, err := su.repository.GetUserQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := su.repository.GetQuotaUsage(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	return &QuotaReport{UserID: quota.UserID, Plan: quota.Plan, Limits: quota.QuotaLimits, Usage: usage}, nil
+}
+
+// ListPlans returns the plans users can be put on
+func (su *StartUpUsecase) ListPlans(ctx context.Context) ([]models.Plan, error) {
+	return su.repository.ListPlans(ctx)
+}
+
+// SetUserQuota puts the user on a plan and returns their new quota
+func (su *StartUpUsecase) SetUserQuota(ctx context.Context, userID string, request UserQuotaRequest) (*QuotaReport, error) {
+	userID = strings.TrimSpace(userID)
+	request.Plan = strings.TrimSpace(request.Plan)
+
+	v := &ValidationError{}
+	if userID == "" {
+		v.add("user_id", "is required")
+	}
+	if request.Plan == "" {
+		request.Plan = models.DefaultPlanName
+	}
+	validateQuotaLimit(v, "max_servers", request.MaxServers)
+	validateQuotaLimit(v, "max_cpu", request.MaxCPU)
+	validateQuotaLimit(v, "max_memory", request.MaxMemory)
+	validateQuotaLimit(v, "max_disk", request.MaxDisk)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	err := su.repository.SetUserQuota(ctx, userID, request.Plan, repository.UserQuotaOverrides{
+		MaxServers: request.MaxServers,
+		MaxCPU:     request.MaxCPU,
+		MaxMemory:  request.MaxMemory,
+		MaxDisk:    request.MaxDisk,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return su.quotaReport(ctx, userID)
+}
+
+func validateQuotaLimit(v *ValidationError, field string, limit *int) {
+	if limit != nil && *limit < 0 {
+		v.add(field, "must not be negative")
+	}
+}
+
+// serverQuota fails with a QuotaExceededError when the user can not create another server
+func serverQuota(quota *models.UserQuota, usage *models.QuotaUsage) error {
+	if usage.Servers+1 > quota.Servers {
+		return &QuotaExceededError{Resource: QuotaResourceServers, Limit: quota.Servers, Used: usage.Servers, Requested: 1}
+	}
+	return nil
+}
+
+// resourceQuota returns the check that fails with a QuotaExceededError when reserving the resources on top
+// of the usage would take the user past their quota
+func resourceQuota(resources models.ServerResources) repository.QuotaCheck {
+	return func(quota *models.UserQuota, usage *models.QuotaUsage) error {
+		checks := []struct {
+			resource           string
+			limit, used, asked int
+		}{
+			{QuotaResourceCPU, quota.CPU, usage.CPU, resources.CPU},
+			{QuotaResourceMemory, quota.Memory, usage.Memory, resources.Memory},
+			{QuotaResourceDisk, quota.Disk, usage.Disk, resources.Disk},
+		}
+		for _, check := range checks {
+			if check.used+check.asked > check.limit {
+				return &QuotaExceededError{Resource: check.resource, Limit: check.limit, Used: check.used, Requested: check.asked}
+			}
+		}
+		return nil
+	}
+}
+
+// checkResourceQuota fails with a QuotaExceededError when running the server with the resources would take
+// its owner past their quota. The server's current reservation is replaced by resources. It only answers
+// requests early, reserveServerResources checks again before a job is registered.
+func (su *StartUpUsecase) checkResourceQuota(ctx context.Context, server *models.GameServerInfo, resources models.ServerResources) error {
+	quota, err := su.repository.GetUserQuota(ctx, server.UserID)
+	if err != nil {
+		return err
+	}
+	usage, err := su.repository.GetQuotaUsage(ctx, server.UserID, server.ID)
+	if err != nil {
+		return err
+	}
+	return resourceQuota(resources)(quota, usage)
+}
+
+// checkServerResources checks the quota for the resources the server currently reserves
+func (su *StartUpUsecase) checkServerResources(ctx context.Context, server *models.GameServerInfo) error {
+	resources, err := su.serverResources(ctx, server)
+	if err != nil {
+		return err
+	}
+	return su.checkResourceQuota(ctx, server, resources)
+}
+
+// reserveServerResources checks the quota for the resources the server reserves under its owner's quota lock
+// and marks an idle server as starting, so workers applying servers of the same user see each other's
+func (su *StartUpUsecase) reserveServerResources(ctx context.Context, server *models.GameServerInfo) error {
+	resources, err := su.serverResources(ctx, server)
+	if err != nil {
+		return err
+	}
+	return su.repository.ReserveServerResources(ctx, server, models.ServerStatusStarting, resourceQuota(resources))
+}
+
+// serverResources returns what the server's job reserves
+func (su *StartUpUsecase) serverResources(ctx context.Context, server *models.GameServerInfo) (models.ServerResources, error) {
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if err != nil {
+		return models.ServerResources{}, err
+	}
+	return resolveResources(game, server), nil
+}
+
+// resolveResources applies the server's overrides to the resources of its game
+func resolveResources(game *models.Game, server *models.GameServerInfo) models.ServerResources {
+	resources := models.ServerResources{CPU: game.CPU, Memory: game.Memory, Disk: game.Disk}
+	if server.CPU != nil {
+		resources.CPU = *server.CPU
+	}
+	if server.Memory != nil {
+		resources.Memory = *server.Memory
+	}
+	return resources
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"startup-manager/core/models"
+	"testing"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+var testQuota = &models.UserQuota{
+	UserID: "user-1",
+	Plan:   models.DefaultPlanName,
+	QuotaLimits: models.QuotaLimits{
+		Servers: 3,
+		CPU:     4000,
+		Memory:  8192,
+		Disk:    10000,
+	},
+}
+
+// checkQuotaError fails the test unless err is nil for a nil want or a QuotaExceededError equal to want
+func checkQuotaError(t *testing.T, err error, want *QuotaExceededError) {
+	t.Helper()
+
+	if want == nil {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	var exceeded *QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+	if *exceeded != *want {
+		t.Fatalf("error = %+v, want %+v", exceeded, want)
+	}
+}
+
+func TestServerQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers int
+		want    *QuotaExceededError
+	}{
+		{name: "no servers", servers: 0},
+		{name: "last server", servers: 2},
+		{name: "at the limit", servers: 3, want: &QuotaExceededError{Resource: QuotaResourceServers, Limit: 3, Used: 3, Requested: 1}},
+		{name: "past the limit", servers: 5, want: &QuotaExceededError{Resource: QuotaResourceServers, Limit: 3, Used: 5, Requested: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serverQuota(testQuota, &models.QuotaUsage{Servers: tt.servers})
+			checkQuotaError(t, err, tt.want)
+		})
+	}
+}
+
+func TestResourceQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		used      models.ServerResources
+		requested models.ServerResources
+		want      *QuotaExceededError
+	}{
+		{name: "nothing used", requested: models.ServerResources{CPU: 1000, Memory: 2048, Disk: 300}},
+		{name: "exactly the limit", used: models.ServerResources{CPU: 3000, Memory: 6144, Disk: 9700}, requested: models.ServerResources{CPU: 1000, Memory: 2048, Disk: 300}},
+		{
+			name:      "cpu",
+			used:      models.ServerResources{CPU: 3500},
+			requested: models.ServerResources{CPU: 1000},
+			want:      &QuotaExceededError{Resource: QuotaResourceCPU, Limit: 4000, Used: 3500, Requested: 1000},
+		},
+		{
+			name:      "memory",
+			used:      models.ServerResources{Memory: 8000},
+			requested: models.ServerResources{Memory: 193},
+			want:      &QuotaExceededError{Resource: QuotaResourceMemory, Limit: 8192, Used: 8000, Requested: 193},
+		},
+		{
+			name:      "disk",
+			requested: models.ServerResources{Disk: 10001},
+			want:      &QuotaExceededError{Resource: QuotaResourceDisk, Limit: 10000, Used: 0, Requested: 10001},
+		},
+		{
+			name:      "cpu is reported first",
+			used:      models.ServerResources{CPU: 4000, Memory: 8192},
+			requested: models.ServerResources{CPU: 1, Memory: 1},
+			want:      &QuotaExceededError{Resource: QuotaResourceCPU, Limit: 4000, Used: 4000, Requested: 1},
+		},
+		{
+			name: "usage already past the limit",
+			used: models.ServerResources{CPU: 5000},
+			want: &QuotaExceededError{Resource: QuotaResourceCPU, Limit: 4000, Used: 5000, Requested: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resourceQuota(tt.requested)(testQuota, &models.QuotaUsage{ServerResources: tt.used})
+			checkQuotaError(t, err, tt.want)
+		})
+	}
+}
+
+func TestQuotaExceededErrorMessage(t *testing.T) {
+	err := &QuotaExceededError{Resource: QuotaResourceMemory, Limit: 8192, Used: 8000, Requested: 1024}
+	want := "quota exceeded: memory would be 9024, the limit is 8192"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestResolveResources(t *testing.T) {
+	game := &models.Game{CPU: 1000, Memory: 2048, Disk: 300}
+
+	tests := []struct {
+		name   string
+		server *models.GameServerInfo
+		want   models.ServerResources
+	}{
+		{name: "game resources", server: &models.GameServerInfo{}, want: models.ServerResources{CPU: 1000, Memory: 2048, Disk: 300}},
+		{name: "cpu override", server: &models.GameServerInfo{CPU: intPtr(500)}, want: models.ServerResources{CPU: 500, Memory: 2048, Disk: 300}},
+		{name: "memory override", server: &models.GameServerInfo{Memory: intPtr(4096)}, want: models.ServerResources{CPU: 1000, Memory: 4096, Disk: 300}},
+		{name: "both overrides", server: &models.GameServerInfo{CPU: intPtr(2000), Memory: intPtr(512)}, want: models.ServerResources{CPU: 2000, Memory: 512, Disk: 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveResources(game, tt.server)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("resources = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuotaLimit(t *testing.T) {
+	v := &ValidationError{}
+	validateQuotaLimit(v, "max_servers", nil)
+	validateQuotaLimit(v, "max_cpu", intPtr(0))
+	validateQuotaLimit(v, "max_memory", intPtr(-1))
+
+	if len(v.Fields) != 1 || v.Fields[0].Field != "max_memory" {
+		t.Fatalf("fields = %+v, want only max_memory", v.Fields)
+	}
+}
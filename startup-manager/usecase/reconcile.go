@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Kinds of drift the reconciler reports
+const (
+	// DriftMissingJob is an applied startup whose job is not registered
+	DriftMissingJob = "missing_job"
+	// DriftSpec is a registered job that differs from the active startup's job spec
+	DriftSpec = "spec_drift"
+	// DriftShouldBeStopped is a running job of a server that was stopped
+	DriftShouldBeStopped = "should_be_stopped"
+	// DriftUnmanagedJob is a running job of a server that has no active startup
+	DriftUnmanagedJob = "unmanaged_job"
+	// DriftApplyFailed is a server whose active startup failed to apply
+	DriftApplyFailed = "apply_failed"
+	// DriftOrphanJob is a job in a game server namespace without a live server
+	DriftOrphanJob = "orphan_job"
+	// DriftOrphanNamespace is a game server namespace without a live server
+	DriftOrphanNamespace = "orphan_namespace"
+)
+
+// Actions the reconciler takes on a finding
+const (
+	ReconcileActionReported  = "reported"
+	ReconcileActionReapplied = "reapplied"
+	ReconcileActionStopped   = "stopped"
+	ReconcileActionPurged    = "purged"
+	ReconcileActionDeleted   = "deleted"
+)
+
+const reconcileInterval = 10 * time.Minute
+
+// ReconcilePolicy decides which findings the reconciler fixes, everything else is only reported
+type ReconcilePolicy struct {
+	// Reapply copies the active startup into a new revision that registers it again for missing jobs and spec drift,
+	// and stops jobs of stopped servers
+	Reapply bool `json:"reapply"`
+	// GarbageCollect purges orphaned jobs and deletes orphaned namespaces
+	GarbageCollect bool `json:"garbage_collect"`
+}
+
+// DefaultReconcilePolicy is what the periodic reconciler runs with
+var DefaultReconcilePolicy = ReconcilePolicy{Reapply: true, GarbageCollect: true}
+
+// ReconcileFinding is a difference between the database and nomad and what was done about it
+type ReconcileFinding struct {
+	Kind        string     `json:"kind"`
+	ServerID    string     `json:"server_id,omitempty"`
+	JobID       string     `json:"job_id,omitempty"`
+	Namespace   string     `json:"namespace"`
+	Detail      string     `json:"detail,omitempty"`
+	Diff        []string   `json:"diff,omitempty"`
+	Action      string     `json:"action"`
+	OperationID *uuid.UUID `json:"operation_id,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// ReconcileReport is the outcome of one reconciler run
+type ReconcileReport struct {
+	Policy     ReconcilePolicy    `json:"policy"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Servers    int                `json:"servers"`
+	Skipped    int                `json:"skipped"`
+	Findings   []ReconcileFinding `json:"findings"`
+	Error      string             `json:"error,omitempty"`
+}
+
+type reconcileState struct {
+	// run serializes reconciler runs
+	run  sync.Mutex
+	mu   sync.Mutex
+	last *ReconcileReport
+}
+
+// RunReconciler reconciles with the default policy every reconcileInterval until ctx is cancelled
+func (su *StartUpUsecase) RunReconciler(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report := su.Reconcile(ctx, DefaultReconcilePolicy)
+		su.logger.Info("reconciled servers with nomad",
+			zap.Int("servers", report.Servers),
+			zap.Int("findings", len(report.Findings)),
+			zap.String("error", report.Error))
+	}
+}
+
+// LastReconcileReport returns the report of the latest reconciler run, nil before the first run
+func (su *StartUpUsecase) LastReconcileReport() *ReconcileReport {
+	su.reconciler.mu.Lock()
+	defer su.reconciler.mu.Unlock()
+	return su.reconciler.last
+}
+
+// Reconcile compares every live server and its active startup with the jobs registered in nomad, fixes
+// what the policy allows and garbage collects jobs and namespaces that no live server owns. Servers with
+// changes still in the outbox are skipped, the operation workers are about to change them anyway.
+func (su *StartUpUsecase) Reconcile(ctx context.Context, policy ReconcilePolicy) *ReconcileReport {
+	su.reconciler.run.Lock()
+	defer su.reconciler.run.Unlock()
+
+	report := &ReconcileReport{Policy: policy, StartedAt: time.Now(), Findings: []ReconcileFinding{}}
+	err := su.reconcile(ctx, policy, report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.FinishedAt = time.Now()
+
+	su.reconciler.mu.Lock()
+	su.reconciler.last = report
+	su.reconciler.mu.Unlock()
+
+	return report
+}
+
+func (su *StartUpUsecase) reconcile(ctx context.Context, policy ReconcilePolicy, report *ReconcileReport) error {
+	// nomad is listed before the servers, a server always exists before its job is registered, so a job
+	// or namespace registered meanwhile still has its server in the list and is not collected
+	jobs, err := su.nomadClient.ListServerJobs(ctx)
+	if err != nil {
+		return err
+	}
+	namespaces, err := su.nomadClient.ListServerNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	servers, err := su.repository.ListLiveServers(ctx)
+	if err != nil {
+		return err
+	}
+	pending, err := su.repository.ListServersWithPendingOutboxEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	report.Servers = len(servers)
+	live := make(map[string]bool, len(servers))
+	jobsByServer := make(map[string]nomadapi.ServerJob, len(jobs))
+	for _, job := range jobs {
+		jobsByServer[job.Namespace+"/"+job.ID] = job
+	}
+
+	for i := range servers {
+		server := &servers[i]
+		live[server.Namespace] = true
+
+		serverID, err := uuid.Parse(server.ID)
+		if err != nil {
+			return err
+		}
+		if pending[serverID] {
+			report.Skipped++
+			continue
+		}
+
+		job, registered := jobsByServer[server.Namespace+"/"+server.JobID]
+		finding, err := su.reconcileServer(ctx, policy, serverID, server, job, registered)
+		if err != nil {
+			return err
+		}
+		if finding != nil {
+			report.Findings = append(report.Findings, *finding)
+		}
+	}
+
+	for _, job := range jobs {
+		if live[job.Namespace] {
+			continue
+		}
+		finding := ReconcileFinding{Kind: DriftOrphanJob, JobID: job.ID, Namespace: job.Namespace, Action: ReconcileActionReported}
+		if policy.GarbageCollect {
+			finding.Action = ReconcileActionPurged
+			err = su.nomadClient.DeleteJob(ctx, job.ID, job.Namespace)
+			if err != nil {
+				finding.Error = err.Error()
+			}
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	for _, namespace := range namespaces {
+		if live[namespace] {
+			continue
+		}
+		finding := ReconcileFinding{Kind: DriftOrphanNamespace, Namespace: namespace, Action: ReconcileActionReported}
+		if policy.GarbageCollect {
+			finding.Action = ReconcileActionDeleted
+			err = su.nomadClient.DeleteNamespace(ctx, namespace)
+			if err != nil {
+				finding.Error = err.Error()
+			}
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	return nil
+}
+
+// reconcileServer compares one server with its registered job and returns a finding when they differ
+func (su *StartUpUsecase) reconcileServer(ctx context.Context, policy ReconcilePolicy, serverID uuid.UUID, server *models.GameServerInfo,
+	job nomadapi.ServerJob, registered bool) (*ReconcileFinding, error) {
+	finding := &ReconcileFinding{
+		ServerID:  server.ID,
+		JobID:     server.JobID,
+		Namespace: server.Namespace,
+		Action:    ReconcileActionReported,
+	}
+
+	startup, err := su.repository.GetActiveStartup(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan bool
+	finding.Kind, finding.Detail, plan = serverDrift(server, startup, registered && !job.Stop)
+	if !plan {
+		if finding.Kind == "" {
+			return nil, nil
+		}
+		if finding.Kind == DriftShouldBeStopped && policy.Reapply {
+			su.reconcileWithOperation(ctx, finding, ReconcileActionStopped, func() (*models.Operation, error) {
+				return su.repository.EnqueueOperation(ctx, models.OutboxKindStopServer, serverID, "queued stop by the reconciler")
+			})
+		}
+		return finding, nil
+	}
+
+	jobPlan, err := su.nomadClient.PlanJob(ctx, startup.JobSpec)
+	if err != nil {
+		finding.Kind = DriftSpec
+		finding.Error = err.Error()
+		return finding, nil
+	}
+	finding.Kind, finding.Detail, finding.Diff = planDrift(startup, jobPlan)
+	if finding.Kind == "" {
+		return nil, nil
+	}
+
+	if policy.Reapply {
+		su.reconcileWithOperation(ctx, finding, ReconcileActionReapplied, func() (*models.Operation, error) {
+			// the reapply is a revision of its own, the outcome recorded on the active one stays as it was
+			return su.repository.CreateStartupRevision(ctx, copyStartupRevision(startup))
+		})
+	}
+	return finding, nil
+}
+
+// serverDrift classifies what can be told about a server from its active startup and whether its job is
+// running. An empty kind is no drift, plan is set when only planning the startup's job can tell.
+func serverDrift(server *models.GameServerInfo, startup *models.StartupInfo, running bool) (kind, detail string, plan bool) {
+	switch {
+	case startup == nil:
+		if !running {
+			return "", "", false
+		}
+		return DriftUnmanagedJob, "the server has no active startup but its job is running", false
+
+	case startup.ApplyStatus == models.StartupApplyFailed:
+		detail = fmt.Sprintf("startup revision %d failed to apply", startup.Revision)
+		if startup.ApplyError != nil {
+			detail += ": " + *startup.ApplyError
+		}
+		return DriftApplyFailed, detail, false
+
+	case server.Status == models.ServerStatusStopped:
+		if !running {
+			return "", "", false
+		}
+		return DriftShouldBeStopped, "the server is stopped but its job is running", false
+	}
+	return "", "", true
+}
+
+// planDrift classifies the plan of the active startup's job, an empty kind is a job that matches the startup
+func planDrift(startup *models.StartupInfo, plan *nomadapi.JobPlan) (kind, detail string, diff []string) {
+	switch {
+	case plan == nil || plan.Diff == nil || plan.Diff.Type == "Added":
+		return DriftMissingJob, fmt.Sprintf("the job of startup revision %d is not registered", startup.Revision), nil
+	case plan.Diff.Type == "None":
+		return "", "", nil
+	default:
+		return DriftSpec, fmt.Sprintf("the registered job differs from startup revision %d", startup.Revision), nomadapi.DiffSummary(plan.Diff)
+	}
+}
+
+// reconcileWithOperation queues the operation that fixes the finding and records it on the finding
+func (su *StartUpUsecase) reconcileWithOperation(ctx context.Context, finding *ReconcileFinding, action string, enqueue func() (*models.Operation, error)) {
+	operation, err := enqueue()
+	if err != nil {
+		finding.Error = err.Error()
+		return
+	}
+
+	finding.Action = action
+	finding.OperationID = &operation.ID
+	su.wakeOutboxDispatcher()
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"testing"
+
+	nomadApi "github.com/hashicorp/nomad/api"
+)
+
+func TestServerDrift(t *testing.T) {
+	applyError := "no nodes were eligible"
+	running := &models.GameServerInfo{Status: models.ServerStatusRunning}
+	stopped := &models.GameServerInfo{Status: models.ServerStatusStopped}
+	applied := &models.StartupInfo{Revision: 3, ApplyStatus: models.StartupApplyApplied}
+	failed := &models.StartupInfo{Revision: 4, ApplyStatus: models.StartupApplyFailed, ApplyError: &applyError}
+
+	tests := []struct {
+		name       string
+		server     *models.GameServerInfo
+		startup    *models.StartupInfo
+		jobRunning bool
+		kind       string
+		detail     string
+		plan       bool
+	}{
+		{name: "no startup and no job", server: running},
+		{name: "no startup but a running job", server: running, jobRunning: true, kind: DriftUnmanagedJob, detail: "the server has no active startup but its job is running"},
+		{name: "failed startup", server: running, startup: failed, jobRunning: true, kind: DriftApplyFailed, detail: "startup revision 4 failed to apply: no nodes were eligible"},
+		{name: "failed startup without error", server: running, startup: &models.StartupInfo{Revision: 2, ApplyStatus: models.StartupApplyFailed}, kind: DriftApplyFailed, detail: "startup revision 2 failed to apply"},
+		{name: "failed startup of a stopped server", server: stopped, startup: failed, kind: DriftApplyFailed, detail: "startup revision 4 failed to apply: no nodes were eligible"},
+		{name: "stopped server without a job", server: stopped, startup: applied},
+		{name: "stopped server with a running job", server: stopped, startup: applied, jobRunning: true, kind: DriftShouldBeStopped, detail: "the server is stopped but its job is running"},
+		{name: "running server", server: running, startup: applied, jobRunning: true, plan: true},
+		{name: "running server without a job", server: running, startup: applied, plan: true},
+		{name: "pending startup", server: running, startup: &models.StartupInfo{ApplyStatus: models.StartupApplyPending}, plan: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, detail, plan := serverDrift(tt.server, tt.startup, tt.jobRunning)
+			if kind != tt.kind || detail != tt.detail || plan != tt.plan {
+				t.Fatalf("serverDrift() = %q, %q, %v, want %q, %q, %v", kind, detail, plan, tt.kind, tt.detail, tt.plan)
+			}
+		})
+	}
+}
+
+func TestPlanDrift(t *testing.T) {
+	startup := &models.StartupInfo{Revision: 7}
+	edited := &nomadApi.JobDiff{
+		Type:   "Edited",
+		Fields: []*nomadApi.FieldDiff{{Type: "Edited", Name: "Priority", Old: "50", New: "70"}},
+	}
+
+	tests := []struct {
+		name   string
+		plan   *nomadapi.JobPlan
+		kind   string
+		detail string
+		diff   bool
+	}{
+		{name: "no plan", plan: nil, kind: DriftMissingJob, detail: "the job of startup revision 7 is not registered"},
+		{name: "no diff", plan: &nomadapi.JobPlan{}, kind: DriftMissingJob, detail: "the job of startup revision 7 is not registered"},
+		{name: "added", plan: &nomadapi.JobPlan{Diff: &nomadApi.JobDiff{Type: "Added"}}, kind: DriftMissingJob, detail: "the job of startup revision 7 is not registered"},
+		{name: "unchanged", plan: &nomadapi.JobPlan{Diff: &nomadApi.JobDiff{Type: "None"}}},
+		{name: "edited", plan: &nomadapi.JobPlan{Diff: edited}, kind: DriftSpec, detail: "the registered job differs from startup revision 7", diff: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, detail, diff := planDrift(startup, tt.plan)
+			if kind != tt.kind || detail != tt.detail {
+				t.Fatalf("planDrift() = %q, %q, want %q, %q", kind, detail, tt.kind, tt.detail)
+			}
+			if tt.diff != (len(diff) > 0) {
+				t.Fatalf("diff = %v, want a diff: %v", diff, tt.diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+)
+
+const apiKeyColumns = `id, user_id, name, roles, created_at, expires_at, revoked_at, last_used_at`
+
+// UseAPIKey returns the valid key with the hash and records that it was used
+func (sr *StartupRepository) UseAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	// the table name is validated as an identifier when the config is loaded
+	query := `UPDATE ` + sr.authorizerTable + ` SET last_used_at=now()
+		WHERE key_hash=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		RETURNING ` + apiKeyColumns
+
+	var key models.APIKey
+	err := sr.DB.GetContext(ctx, &key, query, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetServerUserID returns the owner of the server, deleted servers included
+func (sr *StartupRepository) GetServerUserID(ctx context.Context, serverID string) (string, error) {
+	var userID string
+	err := sr.DB.GetContext(ctx, &userID, "SELECT user_id FROM gs_info WHERE id=$1", serverID)
+	return userID, err
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+
+	"github.com/google/uuid"
+)
+
+// CreateConsoleSession records the start of a console session
+func (sr *StartupRepository) CreateConsoleSession(ctx context.Context, session *models.ConsoleSession) (uuid.UUID, error) {
+	query := `INSERT INTO console_sessions(server_id,command,remote_addr)VALUES($1,$2,$3) RETURNING id`
+
+	var id uuid.UUID
+	err := sr.DB.QueryRowContext(ctx, query, session.ServerID, session.Command, session.RemoteAddr).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// FinishConsoleSession records how a console session ended
+func (sr *StartupRepository) FinishConsoleSession(ctx context.Context, session *models.ConsoleSession) error {
+	query := `UPDATE console_sessions SET bytes_in=$1, bytes_out=$2, exit_code=$3, error=$4, ended_at=now() WHERE id=$5`
+
+	_, err := sr.DB.ExecContext(ctx, query, session.BytesIn, session.BytesOut, session.ExitCode, session.Error, session.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
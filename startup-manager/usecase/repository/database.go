@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"startup-manager/core/models"
 	core "startup-manager/core/postgres"
@@ -11,57 +10,31 @@ import (
 	"github.com/lib/pq"
 )
 
+const gameColumns = `id, name, description, image, envs, ports, port_labels, volumes, cpu, memory, disk, command, args,
+	coalesce(default_startup_command, '') AS default_startup_command, default_variables, variables, with_db,
+	job_template, job_template_version, console_commands, connect_template, created_at, updated_at, deleted_at`
+
+const serverColumns = `id, user_id, server_name, game_name, image, command, job_id, namespace, status, active_startup_id,
+	cpu, memory, created_at, updated_at, deleted_at`
+
 type StartupRepository struct {
 	core.Postgres
+	// authorizerTable holds the api keys
+	authorizerTable string
 }
 
-func NewStartupRepository(db core.Postgres) *StartupRepository {
+func NewStartupRepository(db core.Postgres, authorizerTable string) *StartupRepository {
 	return &StartupRepository{
 		db,
+		authorizerTable,
 	}
 }
 
-func (sr *StartupRepository) AddStartupParams(ctx context.Context, startup *models.StartupInfo) (string, error) {
-	var starup_id string
-	query := `INSERT INTO startups_info(server_id,variables,command)VALUES($1,$2,$3)returning id`
-	startupVariablesJson, err := json.Marshal(startup.Variables)
-	if err != nil {
-		return "", err
-	}
-	log.Println(startupVariablesJson)
-	err = sr.DB.QueryRowContext(ctx, query, startup.ServerID, startupVariablesJson, startup.StartupCommand).Scan(&starup_id)
-
-	if err != nil {
-		log.Println("err at 38", err)
-		return "", err
-	}
-	return starup_id, nil
-}
-
-// Get startupInfo based on provided id
-func (sr *StartupRepository) GetStartupParams(ctx context.Context, id string) (*models.StartupInfo, error) {
-	query := `SELECT variables FROM startups_info WHERE id=$1`
-
-	var variables interface{}
-
-	err := sr.DB.Get(&variables, query, id)
-	if err != nil {
-		log.Println("error is", err)
-		return nil, err
-	}
-	var variablesmap map[string]interface{}
-	json.Unmarshal(variables.([]byte), &variablesmap)
+// GetStartupParams returns a non deleted startup revision by id with its apply status
+func (sr *StartupRepository) GetStartupParams(ctx context.Context, id uuid.UUID) (*models.StartupInfo, error) {
+	query := `SELECT ` + startupColumns + ` FROM startups_info WHERE id=$1 AND deleted_at IS NULL`
 
-	var startup_info_str models.StartupInfo
-	startup_info_str.Variables = variablesmap
-	query3 := "SELECT id,server_id,created_at,updated_at,deleted_at from startups_info WHERE id=$1"
-	err = sr.DB.Get(&startup_info_str, query3, id)
-	if err != nil {
-		log.Println("err at 63", err)
-		return nil, err
-	}
-	log.Println(startup_info_str)
-	return &startup_info_str, nil
+	return scanStartup(sr.DB.QueryRowContext(ctx, query, id))
 }
 
 func (sr *StartupRepository) DeleteStartupParams(ctx context.Context, id string) error {
@@ -76,22 +49,8 @@ func (sr *StartupRepository) DeleteStartupParams(ctx context.Context, id string)
 	return nil
 }
 
-func (sr *StartupRepository) UpdateStartupParams(ctx context.Context, startup *models.StartupInfo) (string, error) {
-	var startupID string
-	startup_variables_json, err := json.Marshal(startup.Variables)
-	if err != nil {
-		log.Println(err)
-		return "", err
-	}
-	err = sr.DB.QueryRowContext(ctx, "UPDATE startups_info SET variables=$1,updated_at=now() WHERE id=$2 RETURNING id", startup_variables_json, startup.ID).Scan(&startupID)
-	if err != nil {
-		return "", err
-	}
-	return startupID, nil
-}
-
 func (sr *StartupRepository) GetGameEnvironments(ctx context.Context, game_name string) ([]string, error) {
-	query := "SELECT envs from games where name=$1"
+	query := "SELECT envs from games where name=$1 AND deleted_at IS NULL"
 
 	var envs pq.StringArray
 	err := sr.DB.QueryRowContext(ctx, query, game_name).Scan(&envs)
@@ -117,7 +76,7 @@ func (sr *StartupRepository) GetGame(ctx context.Context, serverId uuid.UUID) (s
 }
 
 func (sr *StartupRepository) GetStartupCommand(ctx context.Context, game string) (string, error) {
-	query := "SELECT default_startup_command from games WHERE name=$1"
+	query := "SELECT default_startup_command from games WHERE name=$1 AND deleted_at IS NULL"
 
 	var default_startup_command string
 	err := sr.DB.QueryRowContext(ctx, query, game).Scan(&default_startup_command)
@@ -128,28 +87,11 @@ func (sr *StartupRepository) GetStartupCommand(ctx context.Context, game string)
 }
 func (sr *StartupRepository) GetGameDetailedInfo(ctx context.Context, game string) (*models.Game, error) {
 	log.Println(game)
-	query := "SELECT * FROM games WHERE name=$1"
+	query := "SELECT " + gameColumns + " FROM games WHERE name=$1 AND deleted_at IS NULL"
 
 	var gameDetail models.Game
 
-	err := sr.DB.QueryRowContext(ctx, query, game).Scan(
-		&gameDetail.ID,
-		&gameDetail.Name,
-		&gameDetail.Description,
-		&gameDetail.Image,
-		&gameDetail.Envs,
-		&gameDetail.Ports,
-		&gameDetail.Volumes,
-		&gameDetail.CPU,
-		&gameDetail.Memory,
-		&gameDetail.Command,
-		&gameDetail.Args,
-		&gameDetail.DefaultStartupCommand,
-		&gameDetail.DefaultVariables,
-		&gameDetail.WithDB,
-		&gameDetail.CreatedAt,
-		&gameDetail.UpdatedAt,
-	)
+	err := sr.DB.GetContext(ctx, &gameDetail, query, game)
 	if err != nil {
 		log.Println(err)
 		return nil, err
@@ -158,6 +100,19 @@ func (sr *StartupRepository) GetGameDetailedInfo(ctx context.Context, game strin
 	return &gameDetail, nil
 }
 
+// UpdateGameJobTemplate stores a new job template for the game and bumps its version
+func (sr *StartupRepository) UpdateGameJobTemplate(ctx context.Context, gameID string, jobTemplate string) (int, error) {
+	query := `UPDATE games SET job_template=$1, job_template_version=job_template_version+1, updated_at=now()
+		WHERE id=$2 RETURNING job_template_version`
+
+	var version int
+	err := sr.DB.QueryRowContext(ctx, query, jobTemplate, gameID).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
 func (sr *StartupRepository) GetServerStartupCommand(ctx context.Context, serverName string) (string, error) {
 	log.Println(serverName)
 
@@ -173,11 +128,55 @@ func (sr *StartupRepository) GetServerStartupCommand(ctx context.Context, server
 	return startupCommand, nil
 }
 
-func (sr *StartupRepository) UpdateGSCommand(ctx context.Context, serverID string, command string) error {
-	_, err := sr.DB.ExecContext(ctx, "UPDATE gs_info SET command=$1, updated_at=now() WHERE id=$2", command, serverID)
+// GetServer returns the non deleted gs_info row with the given id
+func (sr *StartupRepository) GetServer(ctx context.Context, serverID uuid.UUID) (*models.GameServerInfo, error) {
+	query := "SELECT " + serverColumns + " FROM gs_info WHERE id=$1 AND deleted_at IS NULL"
+
+	var server models.GameServerInfo
+	err := sr.DB.GetContext(ctx, &server, query, serverID)
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// SetServerNomadJob persists the nomad job id and namespace the server runs under
+func (sr *StartupRepository) SetServerNomadJob(ctx context.Context, serverID uuid.UUID, jobID, namespace string) error {
+	_, err := sr.DB.ExecContext(ctx, "UPDATE gs_info SET job_id=$1, namespace=$2, updated_at=now() WHERE id=$3", jobID, namespace, serverID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateServerStatus persists the lifecycle status of the server
+func (sr *StartupRepository) UpdateServerStatus(ctx context.Context, serverID uuid.UUID, status string) error {
+	_, err := sr.DB.ExecContext(ctx, "UPDATE gs_info SET status=$1, updated_at=now() WHERE id=$2", status, serverID)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// DeleteServer soft deletes the server together with its startups
+func (sr *StartupRepository) DeleteServer(ctx context.Context, serverID uuid.UUID) error {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE startups_info SET deleted_at=now() WHERE server_id=$1 AND deleted_at IS NULL", serverID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE gs_info SET status=$1, deleted_at=now(), updated_at=now() WHERE id=$2", models.ServerStatusDeleted, serverID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetEventCursor returns the last nomad event index the consumer processed, 0 when it never ran
+func (sr *StartupRepository) GetEventCursor(ctx context.Context, consumer string) (uint64, error) {
+	var index uint64
+	err := sr.DB.GetContext(ctx, &index, "SELECT last_index FROM nomad_event_cursors WHERE consumer=$1", consumer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+// SaveEventCursor stores the last nomad event index the consumer processed
+func (sr *StartupRepository) SaveEventCursor(ctx context.Context, consumer string, index uint64) error {
+	_, err := sr.DB.ExecContext(ctx, `INSERT INTO nomad_event_cursors(consumer, last_index) VALUES($1, $2)
+		ON CONFLICT (consumer) DO UPDATE SET last_index=excluded.last_index, updated_at=now()`, consumer, index)
+	return err
+}
+
+// UpdateServerStatusByJob persists the status of the non deleted server running the nomad job and reports
+// whether it changed
+func (sr *StartupRepository) UpdateServerStatusByJob(ctx context.Context, namespace, jobID, status string) (bool, error) {
+	result, err := sr.DB.ExecContext(ctx, `UPDATE gs_info SET status=$1, updated_at=now()
+		WHERE namespace=$2 AND job_id=$3 AND deleted_at IS NULL AND status<>$1`, status, namespace, jobID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+)
+
+// CreateGame inserts a new game into the catalog and returns it
+func (sr *StartupRepository) CreateGame(ctx context.Context, game *models.Game) (*models.Game, error) {
+	query := `INSERT INTO games(name, description, image, envs, ports, volumes, cpu, memory, command, args,
+		default_startup_command, default_variables, with_db, job_template, console_commands, variables, port_labels,
+		connect_template, disk)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
+		RETURNING ` + gameColumns
+
+	var created models.Game
+	err := sr.DB.GetContext(ctx, &created, query,
+		game.Name,
+		game.Description,
+		game.Image,
+		game.Envs,
+		game.Ports,
+		game.Volumes,
+		game.CPU,
+		game.Memory,
+		game.Command,
+		game.Args,
+		game.DefaultStartupCommand,
+		game.DefaultVariables,
+		game.WithDB,
+		game.JobTemplate,
+		game.ConsoleCommands,
+		game.Variables,
+		game.PortLabels,
+		game.ConnectTemplate,
+		game.Disk,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetGameByID returns the non deleted game with the given id
+func (sr *StartupRepository) GetGameByID(ctx context.Context, id string) (*models.Game, error) {
+	query := "SELECT " + gameColumns + " FROM games WHERE id=$1 AND deleted_at IS NULL"
+
+	var game models.Game
+	err := sr.DB.GetContext(ctx, &game, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// ListGames returns a page of the catalog ordered by name and the total number of matching games
+func (sr *StartupRepository) ListGames(ctx context.Context, search string, limit, offset int) ([]models.Game, int, error) {
+	filter := "deleted_at IS NULL AND ($1 = '' OR name ILIKE '%' || $1 || '%' OR description ILIKE '%' || $1 || '%')"
+
+	var total int
+	err := sr.DB.GetContext(ctx, &total, "SELECT count(*) FROM games WHERE "+filter, search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	games := []models.Game{}
+	query := "SELECT " + gameColumns + " FROM games WHERE " + filter + " ORDER BY name LIMIT $2 OFFSET $3"
+	err = sr.DB.SelectContext(ctx, &games, query, search, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return games, total, nil
+}
+
+// UpdateGame replaces the game's definition. The job template version is bumped when the template changes
+// and servers are moved along when the game is renamed.
+func (sr *StartupRepository) UpdateGame(ctx context.Context, game *models.Game) (*models.Game, error) {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previousName string
+	err = tx.GetContext(ctx, &previousName, "SELECT name FROM games WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE games SET name=$1, description=$2, image=$3, envs=$4, ports=$5, volumes=$6, cpu=$7, memory=$8,
+		command=$9, args=$10, default_startup_command=$11, default_variables=$12, with_db=$13,
+		job_template_version=CASE WHEN job_template <> $14 THEN job_template_version+1 ELSE job_template_version END,
+		job_template=$14, console_commands=$15, variables=$16, port_labels=$17, connect_template=$18, disk=$19,
+		updated_at=now()
+		WHERE id=$20
+		RETURNING ` + gameColumns
+
+	var updated models.Game
+	err = tx.GetContext(ctx, &updated, query,
+		game.Name,
+		game.Description,
+		game.Image,
+		game.Envs,
+		game.Ports,
+		game.Volumes,
+		game.CPU,
+		game.Memory,
+		game.Command,
+		game.Args,
+		game.DefaultStartupCommand,
+		game.DefaultVariables,
+		game.WithDB,
+		game.JobTemplate,
+		game.ConsoleCommands,
+		game.Variables,
+		game.PortLabels,
+		game.ConnectTemplate,
+		game.Disk,
+		game.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if previousName != updated.Name {
+		_, err = tx.ExecContext(ctx, "UPDATE gs_info SET game_name=$1, updated_at=now() WHERE game_name=$2 AND deleted_at IS NULL", updated.Name, previousName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &updated, tx.Commit()
+}
+
+// DeleteGame soft deletes the game
+func (sr *StartupRepository) DeleteGame(ctx context.Context, id string) error {
+	_, err := sr.DB.ExecContext(ctx, "UPDATE games SET deleted_at=now(), updated_at=now() WHERE id=$1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CountGameServers returns how many non deleted servers run the game
+func (sr *StartupRepository) CountGameServers(ctx context.Context, gameName string) (int, error) {
+	var count int
+	err := sr.DB.GetContext(ctx, &count, "SELECT count(*) FROM gs_info WHERE game_name=$1 AND deleted_at IS NULL", gameName)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const operationColumns = `id, kind, server_id, state, messages, result, error, created_at, updated_at, started_at, finished_at`
+
+// appendOperationMessage is the SQL expression that adds the message parameter to an operation's messages
+func appendOperationMessage(param string) string {
+	return "messages || jsonb_build_array(jsonb_build_object('at', now(), 'message', " + param + "::text))"
+}
+
+// EnqueueOperation creates a queued operation for the server together with the outbox entry that performs it
+func (sr *StartupRepository) EnqueueOperation(ctx context.Context, kind string, serverID uuid.UUID, message string) (*models.Operation, error) {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	operation, err := enqueueOperation(ctx, tx, kind, serverID, nil, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return operation, tx.Commit()
+}
+
+func enqueueOperation(ctx context.Context, tx *sqlx.Tx, kind string, serverID uuid.UUID, startupID *uuid.UUID, message string) (*models.Operation, error) {
+	query := `INSERT INTO operations(kind, server_id, messages)
+		VALUES($1, $2, jsonb_build_array(jsonb_build_object('at', now(), 'message', $3::text)))
+		RETURNING ` + operationColumns
+
+	var operation models.Operation
+	err := tx.GetContext(ctx, &operation, query, kind, serverID, message)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO outbox(kind, server_id, startup_id, operation_id) VALUES($1, $2, $3, $4)",
+		kind, serverID, startupID, operation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+// GetOperation returns the operation with the given id
+func (sr *StartupRepository) GetOperation(ctx context.Context, id uuid.UUID) (*models.Operation, error) {
+	var operation models.Operation
+	err := sr.DB.GetContext(ctx, &operation, "SELECT "+operationColumns+" FROM operations WHERE id=$1", id)
+	if err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// StartOperation moves a queued operation to running and adds the progress message
+func (sr *StartupRepository) StartOperation(ctx context.Context, id uuid.UUID, message string) error {
+	_, err := sr.DB.ExecContext(ctx, `UPDATE operations SET state=$1, started_at=coalesce(started_at, now()),
+		messages=`+appendOperationMessage("$2")+`, updated_at=now() WHERE id=$3`,
+		models.OperationStateRunning, message, id)
+	return err
+}
+
+// AddOperationMessage adds a progress message to the operation
+func (sr *StartupRepository) AddOperationMessage(ctx context.Context, id uuid.UUID, message string) error {
+	_, err := sr.DB.ExecContext(ctx, "UPDATE operations SET messages="+appendOperationMessage("$1")+", updated_at=now() WHERE id=$2",
+		message, id)
+	return err
+}
+
+func finishOperation(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, state string, result models.OperationResult, errMessage *string, message string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE operations SET state=$1, result=$2, error=$3, messages=`+appendOperationMessage("$4")+`,
+		started_at=coalesce(started_at, now()), finished_at=now(), updated_at=now() WHERE id=$5`,
+		state, result, errMessage, message, id)
+	return err
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"startup-manager/core/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const outboxColumns = `id, kind, server_id, startup_id, operation_id, attempts, last_error, next_attempt_at, created_at, processed_at`
+
+// OutboxOutcome is how a processed outbox entry ended
+type OutboxOutcome struct {
+	// OperationState is the final state of the entry's operation
+	OperationState string
+	Result         models.OperationResult
+	Error          *string
+	Message        string
+	// StartupStatus is the apply status stored on the entry's startup revision, if it has one
+	StartupStatus string
+}
+
+// ClaimOutboxEntry takes the oldest due entry and hides it from other workers for the lease. Entries of a
+// server are claimed one at a time in the order they were queued, an entry waits while an older entry of
+// the same server is in flight or waiting for its retry. An entry a worker does not finish within the
+// lease, for example because the service restarted, becomes due again. It returns sql.ErrNoRows when no
+// entry is due.
+func (sr *StartupRepository) ClaimOutboxEntry(ctx context.Context, lease time.Duration) (*models.OutboxEntry, error) {
+	query := `UPDATE outbox SET attempts=attempts+1, next_attempt_at=now() + make_interval(secs => $1)
+		WHERE id = (
+			SELECT id FROM outbox WHERE id IN (
+				SELECT DISTINCT ON (server_id) id FROM outbox WHERE processed_at IS NULL
+				ORDER BY server_id, created_at, id
+			) AND processed_at IS NULL AND next_attempt_at <= now()
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxColumns
+
+	entry := &models.OutboxEntry{}
+	err := sr.DB.GetContext(ctx, entry, query, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RetryOutboxEntry schedules the entry's next attempt and records the error on its startup revision and operation
+func (sr *StartupRepository) RetryOutboxEntry(ctx context.Context, entry *models.OutboxEntry, delay time.Duration, applyErr string) error {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE outbox SET next_attempt_at=now() + make_interval(secs => $1), last_error=$2 WHERE id=$3",
+		delay.Seconds(), applyErr, entry.ID)
+	if err != nil {
+		return err
+	}
+
+	if entry.StartupID != nil {
+		_, err = tx.ExecContext(ctx, "UPDATE startups_info SET apply_error=$1, updated_at=now() WHERE id=$2", applyErr, *entry.StartupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry.OperationID != nil {
+		message := fmt.Sprintf("attempt %d failed, retrying in %s: %s", entry.Attempts, delay, applyErr)
+		_, err = tx.ExecContext(ctx, "UPDATE operations SET messages="+appendOperationMessage("$1")+", updated_at=now() WHERE id=$2",
+			message, *entry.OperationID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FinishOutboxEntry marks the entry processed and stores the outcome on its startup revision and operation
+func (sr *StartupRepository) FinishOutboxEntry(ctx context.Context, entry *models.OutboxEntry, outcome OutboxOutcome) error {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE outbox SET processed_at=now(), last_error=$1 WHERE id=$2", outcome.Error, entry.ID)
+	if err != nil {
+		return err
+	}
+
+	if entry.StartupID != nil && outcome.StartupStatus != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE startups_info SET apply_status=$1, apply_error=$2,
+			applied_at=CASE WHEN $1 = 'applied' THEN now() ELSE applied_at END, updated_at=now() WHERE id=$3`,
+			outcome.StartupStatus, outcome.Error, *entry.StartupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entry.OperationID != nil {
+		err = finishOperation(ctx, tx, *entry.OperationID, outcome.OperationState, outcome.Result, outcome.Error, outcome.Message)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListServersWithPendingOutboxEntries returns the servers with changes still waiting to be applied
+func (sr *StartupRepository) ListServersWithPendingOutboxEntries(ctx context.Context) (map[uuid.UUID]bool, error) {
+	ids := []uuid.UUID{}
+	err := sr.DB.SelectContext(ctx, &ids, "SELECT DISTINCT server_id FROM outbox WHERE processed_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		pending[id] = true
+	}
+	return pending, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"startup-manager/core/models"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// QuotaCheck decides from the user's quota and current usage whether a change may go ahead
+type QuotaCheck func(quota *models.UserQuota, usage *models.QuotaUsage) error
+
+// UserQuotaOverrides are the limits of a user that differ from their plan, nil keeps the plan's
+type UserQuotaOverrides struct {
+	MaxServers *int
+	MaxCPU     *int
+	MaxMemory  *int
+	MaxDisk    *int
+}
+
+// idleServerStatuses are the states of servers that hold no nomad resources
+var idleServerStatuses = pq.StringArray{models.ServerStatusCreated, models.ServerStatusStopped, models.ServerStatusDeleted}
+
+const planColumns = `id, name, max_servers, max_cpu, max_memory, max_disk, max_server_cpu, max_server_memory,
+	created_at, updated_at`
+
+// GetUserQuota returns the user's plan and limits, users without a quota row are on the default plan
+func (sr *StartupRepository) GetUserQuota(ctx context.Context, userID string) (*models.UserQuota, error) {
+	return getUserQuota(ctx, sr.DB, userID)
+}
+
+func getUserQuota(ctx context.Context, q sqlx.QueryerContext, userID string) (*models.UserQuota, error) {
+	query := `SELECT u.user_id, p.name AS plan_name,
+			coalesce(q.max_servers, p.max_servers) AS max_servers,
+			coalesce(q.max_cpu, p.max_cpu) AS max_cpu,
+			coalesce(q.max_memory, p.max_memory) AS max_memory,
+			coalesce(q.max_disk, p.max_disk) AS max_disk,
+			p.max_server_cpu, p.max_server_memory
+		FROM (SELECT $1::text AS user_id) u
+		LEFT JOIN user_quotas q ON q.user_id = u.user_id
+		JOIN plans p ON p.id = coalesce(q.plan_id, (SELECT id FROM plans WHERE name=$2))`
+
+	var quota models.UserQuota
+	err := sqlx.GetContext(ctx, q, &quota, query, userID, models.DefaultPlanName)
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// GetQuotaUsage counts the user's servers and sums the resources of the ones holding a nomad job, with their
+// overrides of the game's cpu and memory. The server
+// with exceptServerID is left out, it is what a change is checked for.
+func (sr *StartupRepository) GetQuotaUsage(ctx context.Context, userID, exceptServerID string) (*models.QuotaUsage, error) {
+	return getQuotaUsage(ctx, sr.DB, userID, exceptServerID)
+}
+
+func getQuotaUsage(ctx context.Context, q sqlx.QueryerContext, userID, exceptServerID string) (*models.QuotaUsage, error) {
+	query := `SELECT count(*) AS servers,
+			coalesce(sum(coalesce(s.cpu, g.cpu)) FILTER (WHERE s.status <> ALL($3)), 0) AS cpu,
+			coalesce(sum(coalesce(s.memory, g.memory)) FILTER (WHERE s.status <> ALL($3)), 0) AS memory,
+			coalesce(sum(g.disk) FILTER (WHERE s.status <> ALL($3)), 0) AS disk
+		FROM gs_info s
+		LEFT JOIN games g ON g.name = s.game_name AND g.deleted_at IS NULL
+		WHERE s.user_id=$1 AND s.deleted_at IS NULL AND s.id::text <> $2`
+
+	var usage models.QuotaUsage
+	err := sqlx.GetContext(ctx, q, &usage, query, userID, exceptServerID, idleServerStatuses)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// checkQuota locks the user's quota for the rest of the transaction and runs check against their quota and
+// usage, so concurrent changes of one user are checked one after another and each sees the ones before it
+func checkQuota(ctx context.Context, tx *sqlx.Tx, userID, exceptServerID string, check QuotaCheck) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('user_quota:' || $1))", userID)
+	if err != nil {
+		return err
+	}
+
+	quota, err := getUserQuota(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	usage, err := getQuotaUsage(ctx, tx, userID, exceptServerID)
+	if err != nil {
+		return err
+	}
+	return check(quota, usage)
+}
+
+// ReserveServerResources checks the quota of the server's owner with the server left out of the usage and, if
+// the check passes, moves an idle server to status so its resources count towards the usage of the changes
+// checked after it. The check and the status change are one transaction under the user's quota lock.
+func (sr *StartupRepository) ReserveServerResources(ctx context.Context, server *models.GameServerInfo, status string, check QuotaCheck) error {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkQuota(ctx, tx, server.UserID, server.ID, check)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE gs_info SET status=$1, updated_at=now() WHERE id=$2 AND status = ANY($3)",
+		status, server.ID, idleServerStatuses)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListPlans returns every plan ordered by name
+func (sr *StartupRepository) ListPlans(ctx context.Context) ([]models.Plan, error) {
+	plans := []models.Plan{}
+	err := sr.DB.SelectContext(ctx, &plans, "SELECT "+planColumns+" FROM plans ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// SetUserQuota puts the user on the plan with the overrides, replacing their previous quota. It returns
+// sql.ErrNoRows when there is no plan with the name.
+func (sr *StartupRepository) SetUserQuota(ctx context.Context, userID, planName string, overrides UserQuotaOverrides) error {
+	query := `INSERT INTO user_quotas(user_id, plan_id, max_servers, max_cpu, max_memory, max_disk)
+		SELECT $1, id, $3, $4, $5, $6 FROM plans WHERE name=$2
+		ON CONFLICT (user_id) DO UPDATE SET plan_id=excluded.plan_id, max_servers=excluded.max_servers,
+			max_cpu=excluded.max_cpu, max_memory=excluded.max_memory, max_disk=excluded.max_disk, updated_at=now()`
+
+	result, err := sr.DB.ExecContext(ctx, query, userID, planName,
+		overrides.MaxServers,
+		overrides.MaxCPU,
+		overrides.MaxMemory,
+		overrides.MaxDisk,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
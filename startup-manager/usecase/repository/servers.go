@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+
+	"github.com/google/uuid"
+)
+
+// ServerFilter narrows down ListServers, empty fields are ignored
+type ServerFilter struct {
+	UserID   string
+	GameName string
+	Status   string
+	Search   string
+	Limit    int
+	Offset   int
+}
+
+// CreateServer inserts a new gs_info row and returns it. The owner's quota is checked in the same
+// transaction under their quota lock, so concurrent creates can not pass the server limit together.
+func (sr *StartupRepository) CreateServer(ctx context.Context, server *models.GameServerInfo, check QuotaCheck) (*models.GameServerInfo, error) {
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = checkQuota(ctx, tx, server.UserID, "", check)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO gs_info(id, user_id, server_name, game_name, image, command, job_id, namespace, status)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		RETURNING ` + serverColumns
+
+	var created models.GameServerInfo
+	err = tx.GetContext(ctx, &created, query,
+		server.ID,
+		server.UserID,
+		server.ServerName,
+		server.GameName,
+		server.Image,
+		server.Command,
+		server.JobID,
+		server.Namespace,
+		server.Status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &created, tx.Commit()
+}
+
+// ListServers returns a page of non deleted servers matching the filter and the total number of matches
+func (sr *StartupRepository) ListServers(ctx context.Context, filter ServerFilter) ([]models.GameServerInfo, int, error) {
+	where := `deleted_at IS NULL
+		AND ($1 = '' OR user_id = $1)
+		AND ($2 = '' OR game_name = $2)
+		AND ($3 = '' OR status = $3)
+		AND ($4 = '' OR server_name ILIKE '%' || $4 || '%')`
+	args := []interface{}{filter.UserID, filter.GameName, filter.Status, filter.Search}
+
+	var total int
+	err := sr.DB.GetContext(ctx, &total, "SELECT count(*) FROM gs_info WHERE "+where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	servers := []models.GameServerInfo{}
+	query := "SELECT " + serverColumns + " FROM gs_info WHERE " + where + " ORDER BY created_at DESC LIMIT $5 OFFSET $6"
+	err = sr.DB.SelectContext(ctx, &servers, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return servers, total, nil
+}
+
+// RenameServer changes the server's name and returns the updated row
+func (sr *StartupRepository) RenameServer(ctx context.Context, serverID uuid.UUID, name string) (*models.GameServerInfo, error) {
+	query := "UPDATE gs_info SET server_name=$1, updated_at=now() WHERE id=$2 AND deleted_at IS NULL RETURNING " + serverColumns
+
+	var server models.GameServerInfo
+	err := sr.DB.GetContext(ctx, &server, query, name, serverID)
+	if err != nil {
+		return nil, err
+	}
+	return &server, nil
+}
+
+// ListLiveServers returns every non deleted server
+func (sr *StartupRepository) ListLiveServers(ctx context.Context) ([]models.GameServerInfo, error) {
+	servers := []models.GameServerInfo{}
+	err := sr.DB.SelectContext(ctx, &servers, "SELECT "+serverColumns+" FROM gs_info WHERE deleted_at IS NULL ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"startup-manager/core/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const startupColumns = `id, server_id, revision, variables, coalesce(command, ''), job_spec, cpu, memory, rollback_of, apply_status,
+	apply_error, applied_at, created_at, updated_at, deleted_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// CreateStartupRevision stores the startup as the server's next revision, makes it the active one and
+// queues the operation that applies it to nomad, all in one transaction
+func (sr *StartupRepository) CreateStartupRevision(ctx context.Context, startup *models.StartupInfo) (*models.Operation, error) {
+	variables, err := json.Marshal(startup.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sr.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// locking the server serializes revision numbers per server
+	_, err = tx.ExecContext(ctx, "SELECT 1 FROM gs_info WHERE id=$1 FOR UPDATE", startup.ServerID)
+	if err != nil {
+		return nil, err
+	}
+
+	startup.ApplyStatus = models.StartupApplyPending
+	query := `INSERT INTO startups_info(server_id, revision, variables, command, job_spec, cpu, memory, rollback_of, apply_status)
+		SELECT $1, coalesce(max(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8 FROM startups_info WHERE server_id=$1
+		RETURNING id, revision, created_at`
+	err = tx.QueryRowContext(ctx, query, startup.ServerID, variables, startup.StartupCommand, startup.JobSpec, startup.CPU,
+		startup.Memory, startup.RollbackOf, startup.ApplyStatus).
+		Scan(&startup.ID, &startup.Revision, &startup.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	operation, err := activateStartup(ctx, tx, startup)
+	if err != nil {
+		return nil, err
+	}
+
+	return operation, tx.Commit()
+}
+
+// activateStartup points the server at the startup revision, takes over its command and resource overrides
+// and queues the operation that applies it
+func activateStartup(ctx context.Context, tx *sqlx.Tx, startup *models.StartupInfo) (*models.Operation, error) {
+	_, err := tx.ExecContext(ctx, "UPDATE gs_info SET active_startup_id=$1, command=$2, cpu=$3, memory=$4, updated_at=now() WHERE id=$5",
+		startup.ID, startup.StartupCommand, startup.CPU, startup.Memory, startup.ServerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return enqueueOperation(ctx, tx, models.OutboxKindApplyStartup, startup.ServerID, &startup.ID,
+		fmt.Sprintf("queued startup revision %d", startup.Revision))
+}
+
+// GetStartup returns a startup revision by id, including deleted ones
+func (sr *StartupRepository) GetStartup(ctx context.Context, id uuid.UUID) (*models.StartupInfo, error) {
+	query := `SELECT ` + startupColumns + ` FROM startups_info WHERE id=$1`
+
+	return scanStartup(sr.DB.QueryRowContext(ctx, query, id))
+}
+
+// GetActiveStartup returns the startup revision the server is running, or nil when it has none
+func (sr *StartupRepository) GetActiveStartup(ctx context.Context, serverID uuid.UUID) (*models.StartupInfo, error) {
+	query := `SELECT ` + startupColumns + ` FROM startups_info
+		WHERE id = (SELECT active_startup_id FROM gs_info WHERE id=$1) AND deleted_at IS NULL`
+
+	startup, err := scanStartup(sr.DB.QueryRowContext(ctx, query, serverID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return startup, err
+}
+
+// GetStartupRevision returns a non deleted startup revision of the server
+func (sr *StartupRepository) GetStartupRevision(ctx context.Context, serverID uuid.UUID, revision int) (*models.StartupInfo, error) {
+	query := `SELECT ` + startupColumns + ` FROM startups_info WHERE server_id=$1 AND revision=$2 AND deleted_at IS NULL`
+
+	return scanStartup(sr.DB.QueryRowContext(ctx, query, serverID, revision))
+}
+
+// ListStartupRevisions returns the server's non deleted startup revisions, newest first
+func (sr *StartupRepository) ListStartupRevisions(ctx context.Context, serverID uuid.UUID) ([]models.StartupInfo, error) {
+	query := `SELECT ` + startupColumns + ` FROM startups_info WHERE server_id=$1 AND deleted_at IS NULL ORDER BY revision DESC`
+
+	rows, err := sr.DB.QueryContext(ctx, query, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	startups := []models.StartupInfo{}
+	for rows.Next() {
+		startup, err := scanStartup(rows)
+		if err != nil {
+			return nil, err
+		}
+		startups = append(startups, *startup)
+	}
+	return startups, rows.Err()
+}
+
+func scanStartup(row rowScanner) (*models.StartupInfo, error) {
+	var (
+		startup   models.StartupInfo
+		variables []byte
+	)
+	err := row.Scan(
+		&startup.ID,
+		&startup.ServerID,
+		&startup.Revision,
+		&variables,
+		&startup.StartupCommand,
+		&startup.JobSpec,
+		&startup.CPU,
+		&startup.Memory,
+		&startup.RollbackOf,
+		&startup.ApplyStatus,
+		&startup.ApplyError,
+		&startup.AppliedAt,
+		&startup.CreatedAt,
+		&startup.UpdatedAt,
+		&startup.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variables) > 0 {
+		err = json.Unmarshal(variables, &startup.Variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &startup, nil
+}
+
+// RestorePreviousStartup points the server back at its most recently applied startup revision while the
+// failed revision is still the active one
+func (sr *StartupRepository) RestorePreviousStartup(ctx context.Context, serverID, failedStartupID uuid.UUID) error {
+	query := `UPDATE gs_info SET active_startup_id=previous.id, command=previous.command, cpu=previous.cpu,
+			memory=previous.memory, updated_at=now()
+		FROM (
+			SELECT id, command, cpu, memory FROM startups_info
+			WHERE server_id=$1 AND id<>$2 AND apply_status=$3 AND deleted_at IS NULL
+			ORDER BY applied_at DESC LIMIT 1
+		) previous
+		WHERE gs_info.id=$1 AND gs_info.active_startup_id=$2`
+
+	_, err := sr.DB.ExecContext(ctx, query, serverID, failedStartupID, models.StartupApplyApplied)
+	return err
+}
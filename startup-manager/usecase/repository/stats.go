@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"startup-manager/core/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const statsColumns = `server_id, resolution, bucket, cpu_percent, cpu_percent_max, memory_bytes, memory_bytes_max,
+	memory_limit_bytes, network_reserved_mbits, samples`
+
+// AddStatsSample stores a raw sample, a second sample for the same server and time replaces the first
+func (sr *StartupRepository) AddStatsSample(ctx context.Context, sample *models.ServerStatsSample) error {
+	_, err := sr.DB.ExecContext(ctx, `INSERT INTO server_stats(`+statsColumns+`)
+		VALUES($1, $2, $3, $4, $4, $5, $5, $6, $7, 1)
+		ON CONFLICT (server_id, resolution, bucket) DO UPDATE SET cpu_percent=excluded.cpu_percent,
+			cpu_percent_max=excluded.cpu_percent_max, memory_bytes=excluded.memory_bytes,
+			memory_bytes_max=excluded.memory_bytes_max, memory_limit_bytes=excluded.memory_limit_bytes,
+			network_reserved_mbits=excluded.network_reserved_mbits`,
+		sample.ServerID, models.StatsResolutionRaw, sample.Bucket, sample.CPUPercent, sample.MemoryBytes,
+		sample.MemoryLimitBytes, sample.NetworkReservedMBits)
+	return err
+}
+
+// RollupStats aggregates the samples of resolution from newer than since into buckets of the given size stored
+// as resolution to. Buckets that already exist are recomputed, so partially filled buckets are completed by
+// later rollups as long as since reaches back to their start.
+func (sr *StartupRepository) RollupStats(ctx context.Context, from, to string, bucket time.Duration, since time.Time) error {
+	_, err := sr.DB.ExecContext(ctx, `INSERT INTO server_stats(`+statsColumns+`)
+		SELECT server_id, $2, to_timestamp(floor(extract(epoch FROM bucket) / $3) * $3) AS rollup_bucket,
+			sum(cpu_percent * samples) / sum(samples), max(cpu_percent_max),
+			(sum(memory_bytes * samples) / sum(samples))::bigint, max(memory_bytes_max),
+			max(memory_limit_bytes), max(network_reserved_mbits), sum(samples)
+		FROM server_stats
+		WHERE resolution=$1 AND bucket >= to_timestamp(floor(extract(epoch FROM $4::timestamptz) / $3) * $3)
+		GROUP BY server_id, rollup_bucket
+		ON CONFLICT (server_id, resolution, bucket) DO UPDATE SET cpu_percent=excluded.cpu_percent,
+			cpu_percent_max=excluded.cpu_percent_max, memory_bytes=excluded.memory_bytes,
+			memory_bytes_max=excluded.memory_bytes_max, memory_limit_bytes=excluded.memory_limit_bytes,
+			network_reserved_mbits=excluded.network_reserved_mbits, samples=excluded.samples`,
+		from, to, bucket.Seconds(), since)
+	return err
+}
+
+// PruneStats deletes the samples of resolution older than before and returns how many were deleted
+func (sr *StartupRepository) PruneStats(ctx context.Context, resolution string, before time.Time) (int64, error) {
+	result, err := sr.DB.ExecContext(ctx, "DELETE FROM server_stats WHERE resolution=$1 AND bucket < $2", resolution, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListStatsSamples returns the server's samples of resolution newer than since, oldest first
+func (sr *StartupRepository) ListStatsSamples(ctx context.Context, serverID uuid.UUID, resolution string, since time.Time) ([]models.ServerStatsSample, error) {
+	samples := []models.ServerStatsSample{}
+	err := sr.DB.SelectContext(ctx, &samples, "SELECT "+statsColumns+` FROM server_stats
+		WHERE server_id=$1 AND resolution=$2 AND bucket >= $3 ORDER BY bucket`, serverID, resolution, since)
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
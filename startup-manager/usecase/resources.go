@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+
+	"github.com/google/uuid"
+)
+
+// ErrResourcesUnplaceable is returned when nomad has no room for the server with its new resources
+var ErrResourcesUnplaceable = errors.New("nomad can not place the server with these resources")
+
+// ErrNoActiveStartup is returned when a server is resized before it has a startup to record the resize in
+var ErrNoActiveStartup = errors.New("server has no startup yet, resources can be changed once it has one")
+
+// the least cpu in MHz and memory in MB a server can be given
+const (
+	minServerCPU    = 100
+	minServerMemory = 128
+)
+
+// ResourcesRequest changes a server's cpu in MHz and memory in MB, nil keeps the current value
+type ResourcesRequest struct {
+	CPU    *int `json:"cpu"`
+	Memory *int `json:"memory"`
+}
+
+// ResourcesChange is a resize of a server and whether nomad can place it. Placeable and Plan are nil when
+// the server's job was never registered.
+type ResourcesChange struct {
+	From             models.ServerResources `json:"from"`
+	To               models.ServerResources `json:"to"`
+	Placeable        *bool                  `json:"placeable"`
+	PlacementFailure string                 `json:"placement_failure,omitempty"`
+	Plan             *nomadapi.JobPlan      `json:"plan"`
+}
+
+// ResizeServer checks the new resources against the plan's bounds and the owner's quota and plans the job
+// with them. Unless dryRun is set or nomad can not place the job, they become the server's next startup
+// revision and the operation that registers the job again is queued. Servers without a startup have no
+// history to record the resize in, only a dry run is answered for them.
+func (su *StartUpUsecase) ResizeServer(ctx context.Context, serverID uuid.UUID, request ResourcesRequest, dryRun bool) (*ResourcesChange, *models.Operation, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if err != nil {
+		return nil, nil, err
+	}
+	quota, err := su.repository.GetUserQuota(ctx, server.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = validateResize(request, quota)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resized := *server
+	if request.CPU != nil {
+		resized.CPU = request.CPU
+	}
+	if request.Memory != nil {
+		resized.Memory = request.Memory
+	}
+	change := &ResourcesChange{From: resolveResources(game, server), To: resolveResources(game, &resized)}
+
+	err = su.checkResourceQuota(ctx, &resized, change.To)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	startup, err := su.repository.GetActiveStartup(ctx, serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if startup == nil {
+		if dryRun {
+			return change, nil, nil
+		}
+		return nil, nil, ErrNoActiveStartup
+	}
+
+	spec, err := su.buildStartup(ctx, &resized, startup.Variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	change.Plan, err = su.nomadClient.PlanJob(ctx, spec.jobFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if change.Plan != nil {
+		change.PlacementFailure = nomadapi.PlanPlacementFailure(change.Plan)
+		placeable := change.PlacementFailure == ""
+		change.Placeable = &placeable
+	}
+	if dryRun {
+		return change, nil, nil
+	}
+	if change.PlacementFailure != "" {
+		return change, nil, fmt.Errorf("%w: %s", ErrResourcesUnplaceable, change.PlacementFailure)
+	}
+
+	// the new resources are a startup revision of their own so they show up in the history and roll back
+	operation, err := su.repository.CreateStartupRevision(ctx, &models.StartupInfo{
+		ServerID:       startup.ServerID,
+		Variables:      spec.variables,
+		StartupCommand: spec.command,
+		JobSpec:        spec.jobFile,
+		CPU:            resized.CPU,
+		Memory:         resized.Memory,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	su.wakeOutboxDispatcher()
+
+	return change, operation, nil
+}
+
+// validateResize checks the requested resources against the bounds of a single server of the user's plan
+func validateResize(request ResourcesRequest, quota *models.UserQuota) error {
+	v := &ValidationError{}
+	if request.CPU == nil && request.Memory == nil {
+		v.add("cpu", "cpu or memory is required")
+	}
+	if request.CPU != nil && (*request.CPU < minServerCPU || *request.CPU > quota.ServerCPU) {
+		v.add("cpu", "must be between %d and %d MHz", minServerCPU, quota.ServerCPU)
+	}
+	if request.Memory != nil && (*request.Memory < minServerMemory || *request.Memory > quota.ServerMemory) {
+		v.add("memory", "must be between %d and %d MB", minServerMemory, quota.ServerMemory)
+	}
+	return v.err()
+}
+
+// resourceOverrides returns the cpu and memory the startup revision overrides, keyed by resource
+func resourceOverrides(startup *models.StartupInfo) map[string]interface{} {
+	overrides := map[string]interface{}{}
+	if startup.CPU != nil {
+		overrides[QuotaResourceCPU] = *startup.CPU
+	}
+	if startup.Memory != nil {
+		overrides[QuotaResourceMemory] = *startup.Memory
+	}
+	return overrides
+}
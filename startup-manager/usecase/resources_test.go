@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"reflect"
+	"startup-manager/core/models"
+	"testing"
+)
+
+func TestValidateResize(t *testing.T) {
+	quota := &models.UserQuota{QuotaLimits: models.QuotaLimits{ServerCPU: 2000, ServerMemory: 4096}}
+
+	tests := []struct {
+		name    string
+		request ResourcesRequest
+		fields  []string
+	}{
+		{name: "cpu only", request: ResourcesRequest{CPU: intPtr(1000)}},
+		{name: "memory only", request: ResourcesRequest{Memory: intPtr(1024)}},
+		{name: "lower bounds", request: ResourcesRequest{CPU: intPtr(minServerCPU), Memory: intPtr(minServerMemory)}},
+		{name: "upper bounds", request: ResourcesRequest{CPU: intPtr(2000), Memory: intPtr(4096)}},
+
+		{name: "nothing to change", request: ResourcesRequest{}, fields: []string{"cpu"}},
+		{name: "cpu below min", request: ResourcesRequest{CPU: intPtr(minServerCPU - 1)}, fields: []string{"cpu"}},
+		{name: "cpu above the plan", request: ResourcesRequest{CPU: intPtr(2001)}, fields: []string{"cpu"}},
+		{name: "negative cpu", request: ResourcesRequest{CPU: intPtr(-1000)}, fields: []string{"cpu"}},
+		{name: "memory below min", request: ResourcesRequest{Memory: intPtr(minServerMemory - 1)}, fields: []string{"memory"}},
+		{name: "memory above the plan", request: ResourcesRequest{Memory: intPtr(4097)}, fields: []string{"memory"}},
+		{name: "both out of bounds", request: ResourcesRequest{CPU: intPtr(0), Memory: intPtr(0)}, fields: []string{"cpu", "memory"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResize(tt.request, quota)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			got := fieldNames(t, err)
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateResizeWithoutServerLimits(t *testing.T) {
+	// a plan without per server limits can not be resized into
+	err := validateResize(ResourcesRequest{CPU: intPtr(minServerCPU)}, &models.UserQuota{})
+	got := fieldNames(t, err)
+	if !reflect.DeepEqual(got, []string{"cpu"}) {
+		t.Fatalf("fields = %v, want [cpu]", got)
+	}
+}
+
+func TestResourceOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		startup *models.StartupInfo
+		want    map[string]interface{}
+	}{
+		{name: "none", startup: &models.StartupInfo{}, want: map[string]interface{}{}},
+		{name: "cpu", startup: &models.StartupInfo{CPU: intPtr(500)}, want: map[string]interface{}{QuotaResourceCPU: 500}},
+		{name: "both", startup: &models.StartupInfo{CPU: intPtr(500), Memory: intPtr(1024)}, want: map[string]interface{}{QuotaResourceCPU: 500, QuotaResourceMemory: 1024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resourceOverrides(tt.startup)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("overrides = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"startup-manager/usecase/repository"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrServerExists is returned when the user already has a server with the name
+var ErrServerExists = errors.New("a server with this name already exists")
+
+const maxServerNameLength = 64
+
+// Nomad states reported by GetServerDetails besides the allocation client status
+const (
+	NomadStatusNotRegistered = "not_registered"
+	NomadStatusUnknown       = "unknown"
+)
+
+// CreateServerRequest describes a new game server, the game is looked up by id or by name. UserID is only
+// honoured for admins, everyone else creates servers for themselves.
+type CreateServerRequest struct {
+	UserID     string
+	ServerName string
+	GameID     string
+	GameName   string
+}
+
+// ServerPage is one page of a server listing
+type ServerPage struct {
+	Servers []models.GameServerInfo `json:"servers"`
+	Total   int                     `json:"total"`
+	Limit   int                     `json:"limit"`
+	Offset  int                     `json:"offset"`
+}
+
+// ServerDetails is a server together with its current startup and nomad status
+type ServerDetails struct {
+	Server      *models.GameServerInfo `json:"server"`
+	Startup     *models.StartupInfo    `json:"startup"`
+	NomadStatus string                 `json:"nomad_status"`
+	NomadError  string                 `json:"nomad_error,omitempty"`
+}
+
+// CreateServer creates a gs_info row for the game, the server is provisioned once a startup is added
+func (su *StartUpUsecase) CreateServer(ctx context.Context, request CreateServerRequest) (*models.GameServerInfo, error) {
+	request.ServerName = strings.TrimSpace(request.ServerName)
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserID == "" || !user.IsAdmin() {
+		request.UserID = user.ID
+	}
+
+	v := &ValidationError{}
+	if request.UserID == "" {
+		v.add("user_id", "is required")
+	}
+	validateServerName(v, request.ServerName)
+	if request.GameID == "" && request.GameName == "" {
+		v.add("game_id", "game_id or game is required")
+	} else if _, err := uuid.Parse(request.GameID); request.GameID != "" && err != nil {
+		v.add("game_id", "must be a uuid")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	var game *models.Game
+	if request.GameID != "" {
+		game, err = su.GetGameByID(ctx, request.GameID)
+	} else {
+		game, err = su.repository.GetGameDetailedInfo(ctx, request.GameName)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrGameNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := generateDefaultStartupCommand(game.DefaultStartupCommand, game.DefaultVariables)
+	if err != nil {
+		return nil, err
+	}
+
+	serverID := uuid.New().String()
+	server, err := su.repository.CreateServer(ctx, &models.GameServerInfo{
+		ID:         serverID,
+		UserID:     request.UserID,
+		ServerName: request.ServerName,
+		GameName:   game.Name,
+		Image:      game.Image,
+		Command:    command,
+		JobID:      nomadapi.JobIDForServer(serverID),
+		Namespace:  nomadapi.NamespaceForServer(serverID),
+		Status:     models.ServerStatusCreated,
+	}, serverQuota)
+	if isUniqueViolation(err) {
+		return nil, ErrServerExists
+	}
+	return server, err
+}
+
+// ListServers returns a page of servers matching the filter, users other than admins only see their own
+func (su *StartUpUsecase) ListServers(ctx context.Context, filter repository.ServerFilter) (*ServerPage, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin() {
+		filter.UserID = user.ID
+	}
+	filter.Limit, filter.Offset = pageBounds(filter.Limit, filter.Offset)
+
+	servers, total, err := su.repository.ListServers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServerPage{Servers: servers, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// RenameServer changes the display name of the server
+func (su *StartUpUsecase) RenameServer(ctx context.Context, serverID uuid.UUID, name string) (*models.GameServerInfo, error) {
+	name = strings.TrimSpace(name)
+
+	v := &ValidationError{}
+	validateServerName(v, name)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	_, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := su.repository.RenameServer(ctx, serverID, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrServerNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrServerExists
+	}
+	return server, err
+}
+
+// GetServerDetails returns the server with its active startup and the client status of its newest allocation
+func (su *StartUpUsecase) GetServerDetails(ctx context.Context, serverID uuid.UUID) (*ServerDetails, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	startup, err := su.repository.GetActiveStartup(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &ServerDetails{Server: server, Startup: startup}
+	details.NomadStatus, err = su.nomadClient.CheckJobStatus(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		details.NomadStatus = NomadStatusUnknown
+		if nomadapi.IsNotFound(err) {
+			details.NomadStatus = NomadStatusNotRegistered
+		} else {
+			details.NomadError = err.Error()
+		}
+	}
+
+	return details, nil
+}
+
+func validateServerName(v *ValidationError, name string) {
+	if name == "" {
+		v.add("server_name", "is required")
+	} else if len(name) > maxServerNameLength {
+		v.add("server_name", "must be at most %d characters", maxServerNameLength)
+	}
+}
+
+// StartServer queues an operation that starts the server's stopped nomad job
+func (su *StartUpUsecase) StartServer(ctx context.Context, serverID uuid.UUID) (*models.Operation, error) {
+	return su.enqueueServerOperation(ctx, serverID, models.OutboxKindStartServer, "queued start")
+}
+
+// StopServer queues an operation that stops the server's nomad job without purging it
+func (su *StartUpUsecase) StopServer(ctx context.Context, serverID uuid.UUID) (*models.Operation, error) {
+	return su.enqueueServerOperation(ctx, serverID, models.OutboxKindStopServer, "queued stop")
+}
+
+// RestartServer queues an operation that restarts the task of the server's newest allocation
+func (su *StartUpUsecase) RestartServer(ctx context.Context, serverID uuid.UUID) (*models.Operation, error) {
+	return su.enqueueServerOperation(ctx, serverID, models.OutboxKindRestartServer, "queued restart")
+}
+
+// DeleteServer queues an operation that purges the server's nomad job and soft deletes the server and its startups
+func (su *StartUpUsecase) DeleteServer(ctx context.Context, serverID uuid.UUID) (*models.Operation, error) {
+	return su.enqueueServerOperation(ctx, serverID, models.OutboxKindDeleteServer, "queued delete")
+}
+
+func (su *StartUpUsecase) enqueueServerOperation(ctx context.Context, serverID uuid.UUID, kind, message string) (*models.Operation, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	// starting a stopped server reserves its resources again, the worker checks once more before starting
+	if kind == models.OutboxKindStartServer {
+		err = su.checkServerResources(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	operation, err := su.repository.EnqueueOperation(ctx, kind, serverID, message)
+	if err != nil {
+		return nil, err
+	}
+	su.wakeOutboxDispatcher()
+
+	return operation, nil
+}
+
+func (su *StartUpUsecase) startServer(ctx context.Context, entry *models.OutboxEntry, server *models.GameServerInfo) (models.OperationResult, error) {
+	err := su.reserveServerResources(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	su.operationProgress(ctx, entry, fmt.Sprintf("starting job %s", server.JobID))
+	err = su.nomadClient.StartJob(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return serverStatusResult(models.ServerStatusStarting), su.repository.UpdateServerStatus(ctx, entry.ServerID, models.ServerStatusStarting)
+}
+
+func (su *StartUpUsecase) stopServer(ctx context.Context, entry *models.OutboxEntry, server *models.GameServerInfo) (models.OperationResult, error) {
+	su.operationProgress(ctx, entry, fmt.Sprintf("stopping job %s", server.JobID))
+	err := su.nomadClient.StopJob(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return serverStatusResult(models.ServerStatusStopped), su.repository.UpdateServerStatus(ctx, entry.ServerID, models.ServerStatusStopped)
+}
+
+func (su *StartUpUsecase) restartServer(ctx context.Context, entry *models.OutboxEntry, server *models.GameServerInfo) (models.OperationResult, error) {
+	su.operationProgress(ctx, entry, fmt.Sprintf("restarting job %s", server.JobID))
+	err := su.nomadClient.RestartJob(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return serverStatusResult(models.ServerStatusRestarting), su.repository.UpdateServerStatus(ctx, entry.ServerID, models.ServerStatusRestarting)
+}
+
+func (su *StartUpUsecase) deleteServer(ctx context.Context, entry *models.OutboxEntry, server *models.GameServerInfo) (models.OperationResult, error) {
+	su.operationProgress(ctx, entry, fmt.Sprintf("purging job %s", server.JobID))
+	err := su.nomadClient.DeleteJob(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		if !nomadapi.IsNotFound(err) {
+			return nil, err
+		}
+		// the job was never registered, there is nothing to purge
+		su.logger.Warn("nomad job not found while deleting server",
+			zap.String("server_id", server.ID),
+			zap.String("job_id", server.JobID))
+	}
+
+	return serverStatusResult(models.ServerStatusDeleted), su.repository.DeleteServer(ctx, entry.ServerID)
+}
+
+func serverStatusResult(status string) models.OperationResult {
+	return models.OperationResult{"status": status}
+}
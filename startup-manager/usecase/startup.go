@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+
+	"github.com/google/uuid"
+)
+
+// ErrRevisionNotFound is returned when the server has no startup revision with the given number
+var ErrRevisionNotFound = errors.New("startup revision not found")
+
+// StartupPreview shows what applying startup variables to a server would change. Plan is nil when the
+// server's job was never registered.
+type StartupPreview struct {
+	Command   string                 `json:"command"`
+	Variables map[string]interface{} `json:"variables"`
+	JobSpec   string                 `json:"job_spec"`
+	Plan      *nomadapi.JobPlan      `json:"plan"`
+}
+
+// startupSpec is what a set of startup variables renders to for a server
+type startupSpec struct {
+	game      *models.Game
+	variables map[string]interface{}
+	command   string
+	jobFile   string
+	resources models.ServerResources
+}
+
+// PreviewStartup renders the startup command and job spec for the variables and plans the job against
+// nomad, nothing is stored or registered
+func (su *StartUpUsecase) PreviewStartup(ctx context.Context, serverID uuid.UUID, variables map[string]interface{}) (*StartupPreview, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := su.buildStartup(ctx, server, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := su.nomadClient.PlanJob(ctx, spec.jobFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StartupPreview{
+		Command:   spec.command,
+		Variables: spec.variables,
+		JobSpec:   spec.jobFile,
+		Plan:      plan,
+	}, nil
+}
+
+// buildStartup checks the variables against the server's game and renders the startup command and job spec
+// with the server's resource overrides
+func (su *StartUpUsecase) buildStartup(ctx context.Context, server *models.GameServerInfo, variables map[string]interface{}) (*startupSpec, error) {
+	game, err := su.repository.GetGameDetailedInfo(ctx, server.GameName)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err = resolveVariables(game.Schema(), variables)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := generateStartupCommand(game.DefaultStartupCommand, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := resolveResources(game, server)
+	rendered := *game
+	rendered.CPU = resources.CPU
+	rendered.Memory = resources.Memory
+
+	jobFile, err := GenerateJobFile(&rendered, server.JobID, server.Namespace, command, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	return &startupSpec{
+		game:      game,
+		variables: variables,
+		command:   command,
+		jobFile:   jobFile,
+		resources: resources,
+	}, nil
+}
+
+// StartupRevision is a stored startup of a server together with what changed since the revision before it
+type StartupRevision struct {
+	models.StartupInfo
+	Active      bool             `json:"active"`
+	Changes     []VariableChange `json:"changes"`
+	CommandFrom *string          `json:"command_from,omitempty"`
+	JobSpecDiff []string         `json:"job_spec_diff"`
+	// ResourceChanges lists the cpu and memory overrides that changed, nil values are the game's
+	ResourceChanges []VariableChange `json:"resource_changes"`
+}
+
+// StartupHistory returns the server's startup revisions newest first, each with its diff to the previous one
+func (su *StartUpUsecase) StartupHistory(ctx context.Context, serverID uuid.UUID) ([]StartupRevision, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	startups, err := su.repository.ListStartupRevisions(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]StartupRevision, 0, len(startups))
+	for i, startup := range startups {
+		revision := StartupRevision{
+			StartupInfo: startup,
+			Active:      server.ActiveStartupID != nil && *server.ActiveStartupID == startup.ID,
+		}
+
+		// the first revision is diffed against an empty startup
+		var previous models.StartupInfo
+		if i+1 < len(startups) {
+			previous = startups[i+1]
+		}
+		revision.Changes = diffVariables(previous.Variables, startup.Variables)
+		if previous.StartupCommand != startup.StartupCommand {
+			revision.CommandFrom = &previous.StartupCommand
+		}
+		revision.JobSpecDiff = diffLines(previous.JobSpec, startup.JobSpec)
+		revision.ResourceChanges = diffVariables(resourceOverrides(&previous), resourceOverrides(&startup))
+
+		history = append(history, revision)
+	}
+
+	return history, nil
+}
+
+// RollbackStartup copies an earlier startup revision into a new revision, makes it the active one and queues
+// the operation that registers its job spec again, so the history shows the rollback. Revisions stored
+// before job specs were kept are rendered from their variables with the current template.
+func (su *StartUpUsecase) RollbackStartup(ctx context.Context, serverID uuid.UUID, revision int) (*models.Operation, error) {
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	startup, err := su.repository.GetStartupRevision(ctx, serverID, revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the revision brings back the resource overrides it was rendered with
+	server.CPU = startup.CPU
+	server.Memory = startup.Memory
+
+	if startup.JobSpec == "" {
+		spec, err := su.buildStartup(ctx, server, startup.Variables)
+		if err != nil {
+			return nil, err
+		}
+		startup.StartupCommand = spec.command
+		startup.JobSpec = spec.jobFile
+	}
+
+	err = su.checkServerResources(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback := copyStartupRevision(startup)
+	rollback.RollbackOf = &startup.Revision
+	operation, err := su.repository.CreateStartupRevision(ctx, rollback)
+	if err != nil {
+		return nil, err
+	}
+	su.wakeOutboxDispatcher()
+
+	return operation, nil
+}
+
+// copyStartupRevision returns what a new revision applying the same startup as the given one is stored with
+func copyStartupRevision(startup *models.StartupInfo) *models.StartupInfo {
+	return &models.StartupInfo{
+		ServerID:       startup.ServerID,
+		Variables:      startup.Variables,
+		StartupCommand: startup.StartupCommand,
+		JobSpec:        startup.JobSpec,
+		CPU:            startup.CPU,
+		Memory:         startup.Memory,
+	}
+}
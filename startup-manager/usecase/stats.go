@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"startup-manager/core/models"
+	nomadapi "startup-manager/core/nomad"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	statsSampleInterval = 30 * time.Second
+	statsRollupInterval = 5 * time.Minute
+	statsSampleTimeout  = 10 * time.Second
+)
+
+// statsRange is a history window of GET /servers/:id/stats and the resolution it is drawn from
+type statsRange struct {
+	window     time.Duration
+	resolution string
+}
+
+var statsRanges = map[string]statsRange{
+	"1h":  {window: time.Hour, resolution: models.StatsResolutionRaw},
+	"24h": {window: 24 * time.Hour, resolution: models.StatsResolutionFiveMinute},
+	"7d":  {window: 7 * 24 * time.Hour, resolution: models.StatsResolutionHour},
+}
+
+// statsRetention is how long samples of each resolution are kept, a little longer than the range drawn from them
+var statsRetention = map[string]time.Duration{
+	models.StatsResolutionRaw:        2 * time.Hour,
+	models.StatsResolutionFiveMinute: 48 * time.Hour,
+	models.StatsResolutionHour:       8 * 24 * time.Hour,
+}
+
+// DefaultStatsRange is the history returned when the request does not pick one
+const DefaultStatsRange = "1h"
+
+// ServerStats is the current resource usage of a server and its sampled history
+type ServerStats struct {
+	ServerID   uuid.UUID                  `json:"server_id"`
+	Current    *nomadapi.ResourceUsage    `json:"current"`
+	NomadError string                     `json:"nomad_error,omitempty"`
+	Range      string                     `json:"range"`
+	Resolution string                     `json:"resolution"`
+	History    []models.ServerStatsSample `json:"history"`
+}
+
+// GetServerStats returns the server's live resource usage from nomad and its history over the range. A server
+// that is not running has no current usage, its history is returned anyway.
+func (su *StartUpUsecase) GetServerStats(ctx context.Context, serverID uuid.UUID, rangeName string) (*ServerStats, error) {
+	if rangeName == "" {
+		rangeName = DefaultStatsRange
+	}
+	statsRange, ok := statsRanges[rangeName]
+	if !ok {
+		v := &ValidationError{}
+		v.add("range", "must be one of 1h, 24h, 7d")
+		return nil, v.err()
+	}
+
+	server, err := su.getServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := su.repository.ListStatsSamples(ctx, serverID, statsRange.resolution, time.Now().Add(-statsRange.window))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ServerStats{ServerID: serverID, Range: rangeName, Resolution: statsRange.resolution, History: history}
+	stats.Current, err = su.nomadClient.GetStats(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		stats.NomadError = err.Error()
+	}
+
+	return stats, nil
+}
+
+// RunStatsSampler samples the resource usage of running servers every statsSampleInterval and rolls the samples
+// up into the coarser resolutions every statsRollupInterval until ctx is cancelled
+func (su *StartUpUsecase) RunStatsSampler(ctx context.Context) {
+	sample := time.NewTicker(statsSampleInterval)
+	defer sample.Stop()
+	rollup := time.NewTicker(statsRollupInterval)
+	defer rollup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sample.C:
+			su.sampleStats(ctx)
+		case <-rollup.C:
+			err := su.rollupStats(ctx, time.Now())
+			if err != nil && ctx.Err() == nil {
+				su.logger.Error("cannot roll up server stats", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (su *StartUpUsecase) sampleStats(ctx context.Context) {
+	servers, err := su.repository.ListLiveServers(ctx)
+	if err != nil {
+		su.logger.Error("cannot list servers to sample", zap.Error(err))
+		return
+	}
+
+	for _, server := range servers {
+		if server.Status != models.ServerStatusRunning && server.Status != models.ServerStatusStarting {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := su.sampleServerStats(ctx, &server)
+		if err != nil && !errors.Is(err, nomadapi.ErrAllocationNotRunning) && !nomadapi.IsNotFound(err) {
+			su.logger.Warn("cannot sample server stats", zap.Error(err), zap.String("server_id", server.ID))
+		}
+	}
+}
+
+func (su *StartUpUsecase) sampleServerStats(ctx context.Context, server *models.GameServerInfo) error {
+	serverID, err := uuid.Parse(server.ID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statsSampleTimeout)
+	defer cancel()
+
+	usage, err := su.nomadClient.GetStats(ctx, server.JobID, server.Namespace)
+	if err != nil {
+		return err
+	}
+
+	return su.repository.AddStatsSample(ctx, &models.ServerStatsSample{
+		ServerID:             serverID,
+		Bucket:               usage.Timestamp.Truncate(time.Second),
+		CPUPercent:           usage.CPUPercent,
+		MemoryBytes:          int64(usage.MemoryBytes),
+		MemoryLimitBytes:     int64(usage.MemoryLimitBytes),
+		NetworkReservedMBits: usage.NetworkReservedMBits,
+	})
+}
+
+// rollupStats recomputes the recent 5 minute and hourly buckets and prunes samples past their retention. The
+// windows reach back further than the rollup interval so a missed run does not leave a bucket incomplete.
+func (su *StartUpUsecase) rollupStats(ctx context.Context, now time.Time) error {
+	err := su.repository.RollupStats(ctx, models.StatsResolutionRaw, models.StatsResolutionFiveMinute, 5*time.Minute, now.Add(-15*time.Minute))
+	if err != nil {
+		return err
+	}
+	err = su.repository.RollupStats(ctx, models.StatsResolutionFiveMinute, models.StatsResolutionHour, time.Hour, now.Add(-2*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	for resolution, retention := range statsRetention {
+		_, err := su.repository.PruneStats(ctx, resolution, now.Add(-retention))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,93 +2,127 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"text/template"
-	"log"
-	"regexp"
+	"startup-manager/core/auth"
 	"startup-manager/core/logger"
 	"startup-manager/core/models"
 	nomadapi "startup-manager/core/nomad"
 	"startup-manager/usecase/repository"
-	"strings"
 
 	"github.com/google/uuid"
 )
 
-type ServerParams struct {
-	StartupCommand string
-	Variables      map[string]interface{}
-}
+// ErrServerNotFound is returned when the gs_info row does not exist or is deleted
+var ErrServerNotFound = errors.New("server not found")
 
 type StartUpUsecase struct {
 	logger      logger.Logger
 	repository  *repository.StartupRepository
 	nomadClient *nomadapi.NomadClient
+	outboxWake  chan struct{}
+	reconciler  reconcileState
+	connections connectionCache
+	// jwtVerifier is nil when no jwks is configured, only api keys are accepted then
+	jwtVerifier *auth.JWTVerifier
 }
 
-func NewStartUpUsecase(logger logger.Logger, repository *repository.StartupRepository, nomadClient *nomadapi.NomadClient) *StartUpUsecase {
+func NewStartUpUsecase(logger logger.Logger, repository *repository.StartupRepository, nomadClient *nomadapi.NomadClient,
+	jwtVerifier *auth.JWTVerifier) *StartUpUsecase {
 	return &StartUpUsecase{
 		logger:      logger,
 		repository:  repository,
 		nomadClient: nomadClient,
+		outboxWake:  make(chan struct{}, 1),
+		jwtVerifier: jwtVerifier,
 	}
 }
 
-func (su *StartUpUsecase) AddStartup(ctx context.Context, startup *models.StartupInfo) (string, error) {
+// AddStartup stores the variables as the server's next startup revision and queues the operation that
+// registers its job with nomad
+func (su *StartUpUsecase) AddStartup(ctx context.Context, startup *models.StartupInfo) (*models.Operation, error) {
 
-	command, err := su.GetGameStartupCommand(ctx, startup.ServerID)
+	server, err := su.getServer(ctx, startup.ServerID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	log.Println(command, "*********")
-	filledcommand, err := generateStartupCommand(command, startup.Variables)
+	spec, err := su.buildStartup(ctx, server, startup.Variables)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	log.Println(filledcommand)
-	startup.StartupCommand = filledcommand
-	log.Println(startup.StartupCommand)
-	startup_id, err := su.repository.AddStartupParams(ctx, startup)
+	err = su.checkResourceQuota(ctx, server, spec.resources)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	log.Println(startup.StartupCommand)
-	err = su.repository.UpdateGSCommand(ctx, startup.ServerID.String(), startup.StartupCommand)
-
+	startup.Variables = spec.variables
+	startup.CPU = server.CPU
+	startup.Memory = server.Memory
+	startup.StartupCommand = spec.command
+	startup.JobSpec = spec.jobFile
+	// the job is registered by the operation workers once the revision is committed
+	operation, err := su.repository.CreateStartupRevision(ctx, startup)
 	if err != nil {
-		log.Println(err)
-		return "", err
+		return nil, err
 	}
-	jobFile,err:=GenerateJobFile(startup.StartupCommand,startup.Variables)
-	// // TODO:-using nomad client change the variables of the game server.
-	// err = su.ChangeStartupVariables(startup.Variables, "cs2-server")
-	// if err != nil {
-	// 	su.logger.Error("error in updating variables", zap.Error(err))
-	// 	return startup_id, err
-	// }
-
-		err=su.nomadClient.RegisterJob(ctx,jobFile)
-		if err!=nil {
-			log.Println(err)
-			return "",err
-		}
+	su.wakeOutboxDispatcher()
 
-	return startup_id, nil
+	return operation, nil
 
 }
 
+// GetStartup returns a startup revision by id, including whether and how it was applied
 func (su *StartUpUsecase) GetStartup(ctx context.Context, id string) (*models.StartupInfo, error) {
-	return su.repository.GetStartupParams(ctx, id)
+	startupID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrRevisionNotFound
+	}
+	startup, err := su.repository.GetStartupParams(ctx, startupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = su.authorizeServer(ctx, startup.ServerID)
+	if err != nil {
+		return nil, err
+	}
+	return startup, nil
 }
 
-func (su *StartUpUsecase) ChangeStartupVariables(variables map[string]interface{}, jobID string) error {
-	// Create a Nomad API client
-	return su.nomadClient.UpdateJobVariables(context.Background(), variables, jobID, jobID, jobID)
+// getServer loads a game server the user of ctx has access to and assigns its nomad job id and namespace
+// on first use
+func (su *StartUpUsecase) getServer(ctx context.Context, serverID uuid.UUID) (*models.GameServerInfo, error) {
+	server, err := su.repository.GetServer(ctx, serverID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrServerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = canAccess(ctx, server.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if server.JobID == "" || server.Namespace == "" {
+		server.JobID = nomadapi.JobIDForServer(server.ID)
+		server.Namespace = nomadapi.NamespaceForServer(server.ID)
+		err = su.repository.SetServerNomadJob(ctx, serverID, server.JobID, server.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	return server, nil
 }
 
 func (su *StartUpUsecase) DeleteStartupInfo(ctx context.Context, id string) error {
+	_, err := su.GetStartup(ctx, id)
+	if err != nil {
+		return err
+	}
 	return su.repository.DeleteStartupParams(ctx, id)
 }
 
@@ -97,8 +131,11 @@ func (su *StartUpUsecase) GetGameEnvironments(ctx context.Context, game_name str
 }
 
 func (su *StartUpUsecase) GetGameStartupCommand(ctx context.Context, serverID uuid.UUID) (string, error) {
+	err := su.authorizeServer(ctx, serverID)
+	if err != nil {
+		return "", err
+	}
 	game, err := su.repository.GetGame(ctx, serverID)
-	log.Println("game----------->", game)
 	if err != nil {
 		return "", err
 	}
@@ -115,6 +152,46 @@ func (su *StartUpUsecase) GetGameInfo(ctx context.Context, game string) (*models
 	return su.repository.GetGameDetailedInfo(ctx, game)
 }
 
+// UpdateGameJobTemplate validates the job template against nomad and stores it as the game's next template version
+func (su *StartUpUsecase) UpdateGameJobTemplate(ctx context.Context, gameName string, jobTemplate string) (int, error) {
+	game, err := su.repository.GetGameDetailedInfo(ctx, gameName)
+	if err != nil {
+		return 0, err
+	}
+	game.JobTemplate = jobTemplate
+
+	err = su.validateJobTemplate(ctx, game)
+	if err != nil {
+		return 0, err
+	}
+
+	return su.repository.UpdateGameJobTemplate(ctx, game.ID, jobTemplate)
+}
+
+// validateJobTemplate renders the game's job template with its default variables and lets nomad parse the result
+func (su *StartUpUsecase) validateJobTemplate(ctx context.Context, game *models.Game) error {
+	defaults, err := parseVariables(game.DefaultVariables)
+	if err != nil {
+		return err
+	}
+	variables := make(map[string]interface{}, len(defaults))
+	for key, value := range defaults {
+		variables[key] = value
+	}
+
+	command, err := generateStartupCommand(game.DefaultStartupCommand, variables)
+	if err != nil {
+		return err
+	}
+
+	jobFile, err := GenerateJobFile(game, jobIDForGame(game.Name), "default", command, variables)
+	if err != nil {
+		return err
+	}
+
+	return su.nomadClient.ValidateJob(ctx, jobFile)
+}
+
 func (su *StartUpUsecase) GetDefaultStartupCommand(ctx context.Context, game string) (string, error) {
 	gameInfo, err := su.repository.GetGameDetailedInfo(ctx, game)
 	if err != nil {
@@ -127,144 +204,32 @@ func (su *StartUpUsecase) GetDefaultStartupCommand(ctx context.Context, game str
 	return defaultCommand, nil
 }
 
-// func (su *StartUpUsecase) RunTheScript(ctx context.Context, gameName string) {
-
-// 	gameInfo, err := su.repository.GetGameDetailedInfo(ctx, gameName)
-// 	if err != nil {
-// 		log.Println(err)
-// 	}
-// 	fillHCLTemplate()
-
-// }
-
-// func(su *StartUpUsecase)GetServerStartupCommand(ctx context.Context,serverName string)(string,error){
-
-// }
-
 func generateStartupCommand(command string, variables map[string]interface{}) (string, error) {
-
-	log.Println(command, variables)
+	values := make(map[string]string, len(variables))
 	for key, value := range variables {
-		placeholder := "{{" + key + "}}"
-		command = strings.ReplaceAll(command, placeholder, fmt.Sprintf("%v", value))
+		values[key] = fmt.Sprintf("%v", value)
 	}
 
-	return command, nil
-
-}
-
-func generateDefaultStartupCommand(command string, variables []string) (string, error) {
-	log.Println("command:---", command)
-	log.Println("varible:---", variables)
-
-	for _, v := range variables {
-		parts := strings.SplitN(v, "=", 2)
-		if len(parts) != 2 {
-			return "", errors.New("invalid variable format")
-		}
-		key := parts[0]
-		value := parts[1]
-
-		// Remove surrounding quotes from value
-		value = strings.Trim(value, "\"")
-
-		// Replace all occurrences of key with value in the command string
-		re := regexp.MustCompile("\\{\\{" + key + "\\}\\}")
-		command = re.ReplaceAllString(command, value)
+	values, err := shellValues(values)
+	if err != nil {
+		return "", err
 	}
 
-	return command, nil
-}
-
-func GenerateJobFile(command string,variables map[string]interface{}) (string, error) {
-
-	params := ServerParams{
-		StartupCommand: command,
-		Variables: variables,
-
-	}
+	return renderCommand(command, values)
 
-	// Job file template
-	jobTemplate := `
-job "minecraft-server" {
-  datacenters = ["dc1"]
-
-  group "minecraft-group" {
-    task "minecraft-task" {
-      driver = "raw_exec"
-
-      config {
-        command = "/bin/bash"
-        args = [
-          "-c",
-          <<EOF
-#!/bin/bash
-SERVER_DIR="/mnt/server"
-SERVER_JARFILE="$SERVER_DIR/{{index .Variables "SERVER_JAR"}}"
-EULA_FILE="$SERVER_DIR/eula.txt"
-
-# Agree to the EULA
-
-# Update package repositories and install required packages
-sudo apt update
-sudo apt install -y curl jq
-
-# Retrieve the latest version of Minecraft from Mojang's version_manifest.json
-LATEST_VERSION=$(curl -sSL https://launchermeta.mojang.com/mc/game/version_manifest.json | jq -r '.latest.release')
-
-# Retrieve the download URL for the server JAR file
-MANIFEST_URL=$(curl -sSL https://launchermeta.mojang.com/mc/game/version_manifest.json | jq -r ".versions[] | select(.id == \"$LATEST_VERSION\") | .url")
-DOWNLOAD_URL=$(curl -sSL $MANIFEST_URL | jq -r '.downloads.server.url')
-
-# Download the server JAR file
-curl -o "$SERVER_JARFILE" "$DOWNLOAD_URL"
-
-# Notify the user that the download is complete
-echo "Minecraft server JAR file downloaded successfully!"
-
-# Run the provided startup command
-java -Xms256M -Xmx512M -Dcom.mojang.eula.agree=true -jar "$SERVER_JARFILE" nogui
-
-# Prompt the user for input
-# Start the Minecraft server
-# Insert your command here to start the Minecraft server
-EOF
-        ]
-      }
-
-      resources {
-        cpu    = 500  # adjust according to your server requirements
-        memory = 512  # adjust according to your server requirements
-        network {
-          mbits = 10
-          port "minecraft" {}
-        }
-      }
-    }
-  }
 }
-`
 
-	// Prepare template with job template
-	tmpl, err := template.New("job").Parse(jobTemplate)
+func generateDefaultStartupCommand(command string, variables []string) (string, error) {
+	// Remove surrounding quotes from the values
+	values, err := parseVariables(variables)
 	if err != nil {
-		log.Println("error in preparing job template",err)
 		return "", err
 	}
 
-	// Create buffer to store filled template
-	var filledTemplate strings.Builder
-
-	// Execute template with job params
-	err = tmpl.Execute(&filledTemplate, params)
+	values, err = shellValues(values)
 	if err != nil {
-		log.Println("error in executing job template",err)
 		return "", err
 	}
 
-	// Convert filled template to string and return
-
-	log.Println(filledTemplate.String())
-	return filledTemplate.String(), nil
-
+	return renderCommand(command, values)
 }
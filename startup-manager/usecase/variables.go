@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"startup-manager/core/models"
+	"strconv"
+	"strings"
+)
+
+// resolveVariables checks user supplied startup variables against the game's schema. Unknown variables,
+// values that break a rule and changes to variables that are not user editable are rejected with field
+// errors, missing variables get their default. Values are returned in their rendered string form.
+func resolveVariables(schema models.VariableSchema, values map[string]interface{}) (map[string]interface{}, error) {
+	v := &ValidationError{}
+	resolved := make(map[string]interface{}, len(schema))
+
+	for name := range values {
+		if _, ok := schema.Lookup(name); !ok {
+			v.add("variables."+name, "is not a variable of this game")
+		}
+	}
+
+	for _, spec := range schema {
+		field := "variables." + spec.Name
+
+		value, ok := values[spec.Name]
+		if !ok || value == nil {
+			resolved[spec.Name] = spec.Default
+			continue
+		}
+
+		normalized, err := normalizeVariable(spec, value)
+		if err != nil {
+			v.add(field, "%s", err.Error())
+			continue
+		}
+		if !spec.UserEditable && normalized != spec.Default {
+			v.add(field, "is not editable")
+			continue
+		}
+		resolved[spec.Name] = normalized
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// normalizeVariable validates a single value against its spec and returns its string form
+func normalizeVariable(spec models.VariableSpec, value interface{}) (string, error) {
+	if s, ok := value.(string); ok && containsControl(s) {
+		return "", fmt.Errorf("must not contain control characters")
+	}
+
+	switch spec.Type {
+	case models.VariableTypeInt:
+		var n int64
+		switch typed := value.(type) {
+		case float64:
+			if typed != math.Trunc(typed) {
+				return "", fmt.Errorf("must be an integer")
+			}
+			n = int64(typed)
+		case string:
+			parsed, err := strconv.ParseInt(strings.TrimSpace(typed), 10, 64)
+			if err != nil {
+				return "", fmt.Errorf("must be an integer")
+			}
+			n = parsed
+		default:
+			return "", fmt.Errorf("must be an integer")
+		}
+		if spec.Min != nil && n < *spec.Min {
+			return "", fmt.Errorf("must be at least %d", *spec.Min)
+		}
+		if spec.Max != nil && n > *spec.Max {
+			return "", fmt.Errorf("must be at most %d", *spec.Max)
+		}
+		return strconv.FormatInt(n, 10), nil
+
+	case models.VariableTypeBool:
+		switch typed := value.(type) {
+		case bool:
+			return strconv.FormatBool(typed), nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(typed))
+			if err != nil {
+				return "", fmt.Errorf("must be a boolean")
+			}
+			return strconv.FormatBool(parsed), nil
+		default:
+			return "", fmt.Errorf("must be a boolean")
+		}
+
+	case models.VariableTypeEnum:
+		s := fmt.Sprintf("%v", value)
+		for _, option := range spec.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(spec.Options, ", "))
+
+	case models.VariableTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("must be a string")
+		}
+		if spec.Min != nil && int64(len(s)) < *spec.Min {
+			return "", fmt.Errorf("must be at least %d characters", *spec.Min)
+		}
+		if spec.Max != nil && int64(len(s)) > *spec.Max {
+			return "", fmt.Errorf("must be at most %d characters", *spec.Max)
+		}
+		if spec.Regex != "" {
+			re, err := regexp.Compile("^(?:" + spec.Regex + ")$")
+			if err != nil {
+				return "", fmt.Errorf("has an invalid pattern in the game definition")
+			}
+			if !re.MatchString(s) {
+				return "", fmt.Errorf("must match %s", spec.Regex)
+			}
+		}
+		return s, nil
+
+	default:
+		return "", fmt.Errorf("has unknown type %q", spec.Type)
+	}
+}
+
+// validateSchema checks the variable declarations of a game, every default has to satisfy its own rules
+func validateSchema(v *ValidationError, schema models.VariableSchema) {
+	seen := map[string]bool{}
+
+	for i, spec := range schema {
+		field := fmt.Sprintf("variables[%d]", i)
+
+		if !envNameRegex.MatchString(spec.Name) {
+			v.add(field+".name", "%q is not a valid variable name", spec.Name)
+			continue
+		}
+		if seen[spec.Name] {
+			v.add(field+".name", "%s is declared twice", spec.Name)
+			continue
+		}
+		seen[spec.Name] = true
+
+		switch spec.Type {
+		case models.VariableTypeInt, models.VariableTypeBool, models.VariableTypeString:
+		case models.VariableTypeEnum:
+			if len(spec.Options) == 0 {
+				v.add(field+".options", "enum variables need at least one option")
+				continue
+			}
+		default:
+			v.add(field+".type", "must be one of int, bool, enum, string")
+			continue
+		}
+
+		if spec.Min != nil && spec.Max != nil && *spec.Min > *spec.Max {
+			v.add(field+".min", "must not be greater than max")
+			continue
+		}
+		if spec.Regex != "" {
+			if _, err := regexp.Compile(spec.Regex); err != nil {
+				v.add(field+".regex", "%s", err.Error())
+				continue
+			}
+		}
+
+		if _, err := normalizeVariable(spec, spec.Default); err != nil {
+			v.add(field+".default", "%s", err.Error())
+		}
+	}
+}
+
+// schemaDefaults renders the schema's defaults in the NAME="value" form of games.default_variables
+func schemaDefaults(schema models.VariableSchema) []string {
+	defaults := make([]string, 0, len(schema))
+	for _, spec := range schema {
+		defaults = append(defaults, fmt.Sprintf(`%s="%s"`, spec.Name, spec.Default))
+	}
+	return defaults
+}
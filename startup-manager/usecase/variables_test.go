@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"startup-manager/core/models"
+	"testing"
+)
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+// fieldNames returns the sorted fields of a validation error
+func fieldNames(t *testing.T, err error) []string {
+	t.Helper()
+
+	var v *ValidationError
+	if !errors.As(err, &v) {
+		t.Fatalf("error = %v, want a validation error", err)
+	}
+	fields := make([]string, 0, len(v.Fields))
+	for _, field := range v.Fields {
+		fields = append(fields, field.Field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+var testSchema = models.VariableSchema{
+	{Name: "MAX_PLAYERS", Type: models.VariableTypeInt, Default: "10", Min: int64Ptr(1), Max: int64Ptr(64), UserEditable: true},
+	{Name: "PVP", Type: models.VariableTypeBool, Default: "true", UserEditable: true},
+	{Name: "MODE", Type: models.VariableTypeEnum, Default: "survival", Options: []string{"survival", "creative"}, UserEditable: true},
+	{Name: "MOTD", Type: models.VariableTypeString, Default: "hello", Max: int64Ptr(16), UserEditable: true},
+	{Name: "MAP", Type: models.VariableTypeString, Default: "de_dust2", Regex: `[a-z0-9_]+`, UserEditable: true},
+	{Name: "TICKRATE", Type: models.VariableTypeInt, Default: "64"},
+}
+
+func TestResolveVariables(t *testing.T) {
+	defaults := map[string]interface{}{
+		"MAX_PLAYERS": "10",
+		"PVP":         "true",
+		"MODE":        "survival",
+		"MOTD":        "hello",
+		"MAP":         "de_dust2",
+		"TICKRATE":    "64",
+	}
+	with := func(overrides map[string]interface{}) map[string]interface{} {
+		resolved := map[string]interface{}{}
+		for k, v := range defaults {
+			resolved[k] = v
+		}
+		for k, v := range overrides {
+			resolved[k] = v
+		}
+		return resolved
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{name: "defaults", values: nil, want: defaults},
+		{name: "null keeps the default", values: map[string]interface{}{"MOTD": nil}, want: defaults},
+		{name: "json number", values: map[string]interface{}{"MAX_PLAYERS": float64(32)}, want: with(map[string]interface{}{"MAX_PLAYERS": "32"})},
+		{name: "int string", values: map[string]interface{}{"MAX_PLAYERS": " 64 "}, want: with(map[string]interface{}{"MAX_PLAYERS": "64"})},
+		{name: "bool", values: map[string]interface{}{"PVP": false}, want: with(map[string]interface{}{"PVP": "false"})},
+		{name: "bool string", values: map[string]interface{}{"PVP": "0"}, want: with(map[string]interface{}{"PVP": "false"})},
+		{name: "enum", values: map[string]interface{}{"MODE": "creative"}, want: with(map[string]interface{}{"MODE": "creative"})},
+		{name: "string at max length", values: map[string]interface{}{"MOTD": "sixteen chars!!!"}, want: with(map[string]interface{}{"MOTD": "sixteen chars!!!"})},
+		{name: "regex", values: map[string]interface{}{"MAP": "cs_office"}, want: with(map[string]interface{}{"MAP": "cs_office"})},
+		{name: "not editable but default", values: map[string]interface{}{"TICKRATE": "64"}, want: defaults},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveVariables(testSchema, tt.values)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("resolved = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVariablesRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		fields []string
+	}{
+		{name: "unknown variable", values: map[string]interface{}{"RCON_PASSWORD": "x"}, fields: []string{"variables.RCON_PASSWORD"}},
+		{name: "int below min", values: map[string]interface{}{"MAX_PLAYERS": float64(0)}, fields: []string{"variables.MAX_PLAYERS"}},
+		{name: "int above max", values: map[string]interface{}{"MAX_PLAYERS": "65"}, fields: []string{"variables.MAX_PLAYERS"}},
+		{name: "fraction", values: map[string]interface{}{"MAX_PLAYERS": 1.5}, fields: []string{"variables.MAX_PLAYERS"}},
+		{name: "int from bool", values: map[string]interface{}{"MAX_PLAYERS": true}, fields: []string{"variables.MAX_PLAYERS"}},
+		{name: "not a bool", values: map[string]interface{}{"PVP": "sometimes"}, fields: []string{"variables.PVP"}},
+		{name: "not an option", values: map[string]interface{}{"MODE": "hardcore"}, fields: []string{"variables.MODE"}},
+		{name: "string from number", values: map[string]interface{}{"MOTD": float64(1)}, fields: []string{"variables.MOTD"}},
+		{name: "string too long", values: map[string]interface{}{"MOTD": "seventeen chars!!"}, fields: []string{"variables.MOTD"}},
+		{name: "control characters", values: map[string]interface{}{"MOTD": "a\nb"}, fields: []string{"variables.MOTD"}},
+		{name: "regex is anchored", values: map[string]interface{}{"MAP": "de_dust2; id"}, fields: []string{"variables.MAP"}},
+		{name: "not editable", values: map[string]interface{}{"TICKRATE": "128"}, fields: []string{"variables.TICKRATE"}},
+		{
+			name:   "every problem",
+			values: map[string]interface{}{"MODE": "hardcore", "PVP": 1.0, "OTHER": "x"},
+			fields: []string{"variables.MODE", "variables.OTHER", "variables.PVP"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveVariables(testSchema, tt.values)
+			got := fieldNames(t, err)
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema models.VariableSchema
+		fields []string
+	}{
+		{name: "valid", schema: testSchema, fields: []string{}},
+		{name: "invalid name", schema: models.VariableSchema{{Name: "max-players", Type: models.VariableTypeString}}, fields: []string{"variables[0].name"}},
+		{
+			name: "declared twice",
+			schema: models.VariableSchema{
+				{Name: "MAP", Type: models.VariableTypeString},
+				{Name: "MAP", Type: models.VariableTypeString},
+			},
+			fields: []string{"variables[1].name"},
+		},
+		{name: "unknown type", schema: models.VariableSchema{{Name: "MAP", Type: "float"}}, fields: []string{"variables[0].type"}},
+		{name: "enum without options", schema: models.VariableSchema{{Name: "MODE", Type: models.VariableTypeEnum}}, fields: []string{"variables[0].options"}},
+		{name: "min above max", schema: models.VariableSchema{{Name: "N", Type: models.VariableTypeInt, Default: "1", Min: int64Ptr(5), Max: int64Ptr(1)}}, fields: []string{"variables[0].min"}},
+		{name: "invalid regex", schema: models.VariableSchema{{Name: "MAP", Type: models.VariableTypeString, Regex: "("}}, fields: []string{"variables[0].regex"}},
+		{name: "default breaks its range", schema: models.VariableSchema{{Name: "N", Type: models.VariableTypeInt, Default: "0", Min: int64Ptr(1)}}, fields: []string{"variables[0].default"}},
+		{name: "default not an option", schema: models.VariableSchema{{Name: "MODE", Type: models.VariableTypeEnum, Default: "x", Options: []string{"a"}}}, fields: []string{"variables[0].default"}},
+		{name: "default breaks its regex", schema: models.VariableSchema{{Name: "MAP", Type: models.VariableTypeString, Default: "A", Regex: "[a-z]+"}}, fields: []string{"variables[0].default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &ValidationError{}
+			validateSchema(v, tt.schema)
+
+			got := []string{}
+			for _, field := range v.Fields {
+				got = append(got, field.Field)
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+// TestValidateGameSchema only covers rejected games, a valid game is rendered against nomad
+func TestValidateGameSchema(t *testing.T) {
+	su := &StartUpUsecase{}
+	game := func(schema models.VariableSchema, command string) *models.Game {
+		return &models.Game{
+			Name:                  "cs2",
+			Image:                 "cm2network/cs2:latest",
+			CPU:                   1000,
+			Memory:                2048,
+			DefaultStartupCommand: command,
+			Variables:             schema,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		game   *models.Game
+		fields []string
+	}{
+		{
+			name:   "invalid schema",
+			game:   game(models.VariableSchema{{Name: "MAP", Type: "float"}}, "./srcds +map {{MAP}}"),
+			fields: []string{"variables[0].type"},
+		},
+		{
+			name:   "placeholder without a variable",
+			game:   game(models.VariableSchema{{Name: "MAP", Type: models.VariableTypeString, Default: "de_dust2"}}, "./srcds +map {{MAP}} -maxplayers {{MAX_PLAYERS}}"),
+			fields: []string{"default_startup_command"},
+		},
+		{
+			name:   "variable without a placeholder",
+			game:   game(testSchema, "./srcds +map {{MAP}}"),
+			fields: []string{"default_variables", "default_variables", "default_variables", "default_variables", "default_variables"},
+		},
+		{
+			name:   "quoted placeholder",
+			game:   game(models.VariableSchema{{Name: "MAP", Type: models.VariableTypeString, Default: "de_dust2"}}, `./srcds +map "{{MAP}}"`),
+			fields: []string{"default_startup_command"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := su.validateGame(context.Background(), tt.game)
+			got := fieldNames(t, err)
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Fatalf("fields = %v, want %v (%v)", got, tt.fields, err)
+			}
+		})
+	}
+}